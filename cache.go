@@ -0,0 +1,251 @@
+package main
+
+import (
+	"container/list"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// errCacheMiss is returned by cache backends when nothing is stored for a key
+var errCacheMiss = errors.New("not found in cache")
+
+// cacheBackend is where we store data fetched from Github. Keys look
+// like file paths, eg: `meain/dotfiles/issues.json`.
+type cacheBackend interface {
+	// Load returns the content stored for key and when it was last saved or touched
+	Load(key string) ([]byte, time.Time, error)
+	Save(key string, content []byte) error
+	// Touch marks the content stored for key as fresh
+	Touch(key string) error
+}
+
+func newCacheBackend(kind string, path string, size int) (cacheBackend, error) {
+	switch kind {
+	case "file":
+		if path != "" {
+			cacheLocation = path
+		}
+		return fileCache{}, nil
+	case "memory":
+		return newMemoryCache(size), nil
+	case "sqlite":
+		if path == "" {
+			path = cacheLocation + ".db"
+		}
+		return newSqliteCache(path)
+	}
+	return nil, fmt.Errorf("unknown cache backend %q, use one of [file,memory,sqlite]", kind)
+}
+
+// fileCache stores each key as a file under cacheLocation
+type fileCache struct{}
+
+func (fileCache) Load(key string) ([]byte, time.Time, error) {
+	path := cacheLocation + "/" + key
+
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, errCacheMiss
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return b, fi.ModTime(), nil
+}
+
+func (fileCache) Save(key string, content []byte) error {
+	path := cacheLocation + "/" + key
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			fmt.Println("Unable to create directory for caching:", err)
+		}
+	}
+
+	// Write to a temp file and move it into place so that readers
+	// never see a partially written file
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), fs.FileMode(0644))
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (fileCache) Touch(key string) error {
+	now := time.Now()
+	return os.Chtimes(cacheLocation+"/"+key, now, now)
+}
+
+type memoryCacheEntry struct {
+	key     string
+	content []byte
+	saved   time.Time
+}
+
+// memoryCache keeps at most `size` keys in memory, evicting the
+// least recently used ones first
+type memoryCache struct {
+	size    int
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newMemoryCache(size int) *memoryCache {
+	return &memoryCache{
+		size:    size,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+func (c *memoryCache) Load(key string) ([]byte, time.Time, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, time.Time{}, errCacheMiss
+	}
+
+	c.order.MoveToFront(el)
+	entry := el.Value.(*memoryCacheEntry)
+	return entry.content, entry.saved, nil
+}
+
+func (c *memoryCache) Save(key string, content []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		entry := el.Value.(*memoryCacheEntry)
+		entry.content = content
+		entry.saved = time.Now()
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key, content, time.Now()})
+	for c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
+
+func (c *memoryCache) Touch(key string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return errCacheMiss
+	}
+	el.Value.(*memoryCacheEntry).saved = time.Now()
+	return nil
+}
+
+// sqliteCache stores everything in a single sqlite database file
+type sqliteCache struct {
+	db *sql.DB
+}
+
+func newSqliteCache(path string) (*sqliteCache, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite only allows a single writer and concurrent saves (like
+	// the ones for combined feeds) fail with SQLITE_BUSY if they
+	// use separate connections. The timeout is for other processes
+	// sharing the database.
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`PRAGMA busy_timeout = 5000`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS cache (
+		key TEXT PRIMARY KEY,
+		content BLOB NOT NULL,
+		saved INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteCache{db}, nil
+}
+
+func (c *sqliteCache) Load(key string) ([]byte, time.Time, error) {
+	var content []byte
+	var saved int64
+	err := c.db.QueryRow("SELECT content, saved FROM cache WHERE key = ?", key).Scan(&content, &saved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, errCacheMiss
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return content, time.Unix(0, saved), nil
+}
+
+func (c *sqliteCache) Save(key string, content []byte) error {
+	_, err := c.db.Exec(
+		"INSERT INTO cache (key, content, saved) VALUES (?, ?, ?) ON CONFLICT(key) DO UPDATE SET content = excluded.content, saved = excluded.saved",
+		key, content, time.Now().UnixNano(),
+	)
+	return err
+}
+
+func (c *sqliteCache) Touch(key string) error {
+	res, err := c.db.Exec("UPDATE cache SET saved = ? WHERE key = ?", time.Now().UnixNano(), key)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errCacheMiss
+	}
+	return nil
+}
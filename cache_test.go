@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCacheBackend(t *testing.T, c cacheBackend) {
+	_, _, err := c.Load("meain/dotfiles/issues.json")
+	if !errors.Is(err, errCacheMiss) {
+		t.Fatalf("Expected cache miss, got %v", err)
+	}
+
+	err = c.Save("meain/dotfiles/issues.json", []byte("dummy"))
+	if err != nil {
+		t.Fatalf("Unable to save: %s", err)
+	}
+
+	content, saved, err := c.Load("meain/dotfiles/issues.json")
+	if err != nil {
+		t.Fatalf("Unable to load: %s", err)
+	}
+	if string(content) != "dummy" {
+		t.Fatalf("Invalid content, got %v", string(content))
+	}
+	if time.Since(saved) > time.Minute {
+		t.Fatalf("Invalid saved time %v", saved)
+	}
+
+	err = c.Touch("meain/dotfiles/issues.json")
+	if err != nil {
+		t.Fatalf("Unable to touch: %s", err)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		t.Fatalf("Unable to create temp directory: %s", err)
+	}
+	cacheLocation = dir
+
+	testCacheBackend(t, fileCache{})
+}
+
+func TestMemoryCache(t *testing.T) {
+	testCacheBackend(t, newMemoryCache(10))
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := newMemoryCache(2)
+	c.Save("a", []byte("a"))
+	c.Save("b", []byte("b"))
+	c.Load("a") // makes b the least recently used
+	c.Save("c", []byte("c"))
+
+	if _, _, err := c.Load("b"); !errors.Is(err, errCacheMiss) {
+		t.Fatalf("Least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, _, err := c.Load(key); err != nil {
+			t.Fatalf("Entry %v should not have been evicted", key)
+		}
+	}
+}
+
+func TestSqliteCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		t.Fatalf("Unable to create temp directory: %s", err)
+	}
+
+	c, err := newSqliteCache(dir + "/cache.db")
+	if err != nil {
+		t.Fatalf("Unable to create sqlite cache: %s", err)
+	}
+	testCacheBackend(t, c)
+}
+
+func TestSqliteCacheConcurrentSaves(t *testing.T) {
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		t.Fatalf("Unable to create temp directory: %s", err)
+	}
+
+	c, err := newSqliteCache(dir + "/cache.db")
+	if err != nil {
+		t.Fatalf("Unable to create sqlite cache: %s", err)
+	}
+
+	// same fan out as getMultiIssues
+	var wg sync.WaitGroup
+	errs := make(chan error, multiConcurrency*4)
+	for i := 0; i < multiConcurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				key := "meain/repo" + strconv.Itoa(i) + "/" + strconv.Itoa(j) + ".json"
+				if err := c.Save(key, []byte("[]")); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Unable to save concurrently: %s", err)
+	}
+	if _, _, err := c.Load("meain/repo7/3.json"); err != nil {
+		t.Fatalf("Unable to load saved entry: %s", err)
+	}
+}
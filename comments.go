@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// getRepoComments returns the latest comments across all the issues
+// of a repo, newest first
+func getRepoComments(repo string, cacheTimeout time.Duration) ([]GithubComment, error) {
+	content, err := getCached(repo+"/comments.json", cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		fmt.Println("Fetching comments for " + repo + " from Github")
+		url := baseUrl + repo + "/issues/comments?sort=created&direction=desc&per_page=100"
+		return fetchAll(url, meta, maxPages)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []GithubComment
+	if err := json.Unmarshal(content, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// getIssueComments returns the comments on a single issue, oldest
+// first. Github only lists them in that order, so the first time we
+// fetch the last `maxPages` pages to have the latest comments of long
+// discussions. After that we only fetch what was updated since and
+// merge it into what we have.
+func getIssueComments(repo string, number int64, cacheTimeout time.Duration) ([]GithubComment, error) {
+	key := repo + "/issues/" + strconv.FormatInt(number, 10)
+	content, err := getCached(key+"/comments.json", cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		fmt.Println("Fetching comments for " + repo + "#" + strconv.FormatInt(number, 10) + " from Github")
+		u := baseUrl + key + "/comments?per_page=100"
+
+		stale, _, _ := cache.Load(key + "/comments.json")
+		since := latestUpdate(stale)
+		if since == "" {
+			pages, meta, err := fetchLastPages(u, meta, maxPages)
+			if err != nil {
+				return nil, cacheMeta{}, err
+			}
+			content, err := mergePages(pages)
+			return content, meta, err
+		}
+
+		updates, meta, err := fetchAll(u+"&since="+url.QueryEscape(since), meta, maxPages)
+		if err != nil {
+			return nil, cacheMeta{}, err
+		}
+		content, err := mergeComments(stale, updates)
+		return content, meta, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []GithubComment
+	if err := json.Unmarshal(content, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// mergeComments merges the comments in `updates` into the ones in
+// `stored`, keyed by comment id, oldest first like Github lists them
+func mergeComments(stored []byte, updates []byte) ([]byte, error) {
+	var storedComments, updatedComments []json.RawMessage
+	if err := json.Unmarshal(stored, &storedComments); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(updates, &updatedComments); err != nil {
+		return nil, err
+	}
+
+	comments := map[int64]json.RawMessage{}
+	for _, raw := range append(storedComments, updatedComments...) {
+		var key struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, err
+		}
+		comments[key.ID] = raw
+	}
+
+	var ids []int64
+	for id := range comments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	merged := []json.RawMessage{}
+	for _, id := range ids {
+		merged = append(merged, comments[id])
+	}
+	return json.Marshal(merged)
+}
+
+// getIssue returns a single issue of a repo
+func getIssue(repo string, number int64, cacheTimeout time.Duration) (GithubIssue, error) {
+	key := repo + "/issues/" + strconv.FormatInt(number, 10)
+	content, err := getCached(key+".json", cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		return fetchAll(baseUrl+key, meta, 1)
+	})
+	if err != nil {
+		return GithubIssue{}, err
+	}
+
+	var issue GithubIssue
+	if err := json.Unmarshal(content, &issue); err != nil {
+		return GithubIssue{}, err
+	}
+	issue.Repo = repo
+	return issue, nil
+}
+
+// commentIssueNumber figures out the issue a comment is on from its
+// issue url (https://api.github.com/repos/<org>/<repo>/issues/<n>)
+func commentIssueNumber(comment GithubComment) int64 {
+	n, _ := strconv.ParseInt(comment.IssueURL[strings.LastIndex(comment.IssueURL, "/")+1:], 10, 64)
+	return n
+}
+
+func getCommentFeed(rc RunConfig, cacheTimeout time.Duration) (string, error) {
+	if rc.Repo == "" {
+		return "", errors.New("comment feeds are only supported for single repos")
+	}
+
+	if rc.Issue != 0 {
+		issue, err := getIssue(rc.Repo, rc.Issue, cacheTimeout)
+		if err != nil {
+			return "", err
+		}
+		comments, err := getIssueComments(rc.Repo, rc.Issue, cacheTimeout)
+		if err != nil {
+			return "", err
+		}
+		return generateCommentRss([]GithubIssue{issue}, comments, rc)
+	}
+
+	issues, err := getIssues(rc.Repo, cacheTimeout)
+	if err != nil {
+		return "", err
+	}
+	comments, err := getRepoComments(rc.Repo, cacheTimeout)
+	if err != nil {
+		return "", err
+	}
+	return generateCommentRss(issues, comments, rc)
+}
+
+// generateCommentRss creates a feed with an item per comment on the
+// issues which match the filters. Comments on issues we don't know
+// about are skipped.
+func generateCommentRss(issues []GithubIssue, comments []GithubComment, rc RunConfig) (string, error) {
+	now := time.Now()
+	feed := &feeds.Feed{
+		Title:   rc.Repo + " comments",
+		Link:    &feeds.Link{Href: "https://github.com/" + rc.Repo},
+		Created: now,
+	}
+	if rc.Issue != 0 && len(issues) == 1 {
+		feed.Title = rc.Repo + "#" + strconv.FormatInt(rc.Issue, 10) + ": " + issues[0].Title
+		feed.Link = &feeds.Link{Href: issues[0].HTMLURL}
+	}
+
+	titleTmpl := rc.TitleTemplate
+	if titleTmpl == "" {
+		titleTmpl = titleTemplate
+	}
+	tmpl, err := parseTitleTemplate(titleTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	fdata, err := filterIssues(issues, rc)
+	if err != nil {
+		return "", err
+	}
+	byNumber := map[int64]GithubIssue{}
+	for _, issue := range fdata {
+		byNumber[issue.Number] = issue
+	}
+
+	bots := newBotNode()
+
+	var items []*feeds.Item
+	categories := itemCategories{}
+	for _, comment := range comments {
+		entry, ok := byNumber[commentIssueNumber(comment)]
+		if !ok {
+			continue
+		}
+
+		// the filters apply to the issue, but we don't want bot
+		// comments either if bots are excluded
+		if rc.NoBots && bots.match(GithubIssue{User: comment.User, PerformedViaGithubApp: comment.PerformedViaGithubApp}) {
+			continue
+		}
+
+		entryType := "issue"
+		if entry.PullRequest.URL != "" {
+			entryType = "pr"
+		}
+		var labels []string
+		for _, label := range entry.Labels {
+			labels = append(labels, label.Name)
+		}
+
+		title, err := renderTitle(tmpl, titleData{rc.Repo, entry.Number, entryType, "comment", entry.Title, labels, comment.User.Login})
+		if err != nil {
+			return "", err
+		}
+
+		body := renderBody(comment.Body, comment.HTMLURL, rc.BodyFormat)
+		createTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", comment.CreatedAt)
+		item := &feeds.Item{
+			Title:       title,
+			Link:        &feeds.Link{Href: comment.HTMLURL},
+			Description: body,
+			Content:     body,
+			Author:      &feeds.Author{Name: comment.User.Login},
+			Id:          comment.HTMLURL,
+			Created:     createTime,
+		}
+		items = append(items, item)
+		categories[item] = labels
+	}
+
+	sortItems(items)
+	feed.Items, err = limitItems(items, rc, now)
+	if err != nil {
+		return "", err
+	}
+
+	return renderFeed(feed, categories, rc.Format)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCommentIssueNumber(t *testing.T) {
+	comment := GithubComment{IssueURL: "https://api.github.com/repos/meain/dotfiles/issues/42"}
+	if n := commentIssueNumber(comment); n != 42 {
+		t.Fatalf("Expected issue 42, got %v", n)
+	}
+	if n := commentIssueNumber(GithubComment{}); n != 0 {
+		t.Fatalf("Expected no issue, got %v", n)
+	}
+}
+
+func TestGetIssueComments(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	maxPagesBackup := maxPages
+	defer func() { maxPages = maxPagesBackup }()
+	maxPages = 2
+
+	pageUrl := func(page string) string {
+		return "https://api.github.com/repos/meain/dotfiles/issues/7/comments?per_page=100&page=" + page
+	}
+
+	// long discussion with 3 pages, we should only get the last 2
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7/comments$").
+		MatchParam("page", "3").
+		Reply(200).
+		SetHeader("Link", `<`+pageUrl("2")+`>; rel="prev", <`+pageUrl("1")+`>; rel="first"`).
+		BodyString(`[{"id":3,"updated_at":"2021-09-03T12:44:47Z"}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7/comments$").
+		MatchParam("page", "2").
+		Reply(200).
+		SetHeader("Link", `<`+pageUrl("1")+`>; rel="prev", <`+pageUrl("3")+`>; rel="next", <`+pageUrl("1")+`>; rel="first", <`+pageUrl("3")+`>; rel="last"`).
+		BodyString(`[{"id":2,"updated_at":"2021-09-02T12:44:47Z"}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7/comments$").
+		Reply(200).
+		SetHeader("Link", `<`+pageUrl("2")+`>; rel="next", <`+pageUrl("3")+`>; rel="last"`).
+		BodyString(`[{"id":1,"updated_at":"2021-09-01T12:44:47Z"}]`)
+
+	comments, err := getIssueComments("meain/dotfiles", 7, time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to fetch comments: %s", err)
+	}
+	if len(comments) != 2 || comments[0].ID != 2 || comments[1].ID != 3 {
+		t.Fatalf("Expected the last two pages of comments, got %v", comments)
+	}
+
+	// later refreshes only fetch what changed and merge it in
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7/comments$").
+		MatchParam("since", "2021-09-03T12:44:47Z").
+		Reply(200).
+		BodyString(`[{"id":3,"body":"edited","updated_at":"2021-09-04T12:44:47Z"},{"id":4,"updated_at":"2021-09-04T12:44:47Z"}]`)
+
+	comments, err = getIssueComments("meain/dotfiles", 7, 0)
+	if err != nil {
+		t.Fatalf("Unable to refresh comments: %s", err)
+	}
+	if len(comments) != 3 || comments[1].Body != "edited" || comments[2].ID != 4 {
+		t.Fatalf("Updated comments were not merged, got %v", comments)
+	}
+	if !gock.IsDone() {
+		t.Fatalf("Not all requests were made")
+	}
+}
+
+func TestCommentRssGeneration(t *testing.T) {
+	issues := []GithubIssue{
+		GithubIssue{
+			Number:  1,
+			Title:   "Crash on start",
+			HTMLURL: "https://github.com/meain/dotfiles/issues/1",
+			Labels:  []GithubIssueLabel{GithubIssueLabel{Name: "bug"}},
+		},
+		GithubIssue{
+			Number:  2,
+			Title:   "Add docs",
+			HTMLURL: "https://github.com/meain/dotfiles/pull/2",
+		},
+	}
+	issues[1].PullRequest.URL = "https://api.github.com/repos/meain/dotfiles/pulls/2"
+	comments := []GithubComment{
+		GithubComment{
+			Body:      "Same **here**",
+			CreatedAt: "2021-09-08T12:44:47Z",
+			HTMLURL:   "https://github.com/meain/dotfiles/issues/1#issuecomment-1",
+			IssueURL:  "https://api.github.com/repos/meain/dotfiles/issues/1",
+			User:      GithubUser{Login: "meain", Type: "User"},
+		},
+		GithubComment{
+			Body:      "Coverage report",
+			CreatedAt: "2021-09-09T12:44:47Z",
+			HTMLURL:   "https://github.com/meain/dotfiles/pull/2#issuecomment-2",
+			IssueURL:  "https://api.github.com/repos/meain/dotfiles/issues/2",
+			User:      GithubUser{Login: "codecov[bot]", Type: "Bot"},
+		},
+		GithubComment{
+			Body:      "Looks good",
+			CreatedAt: "2021-09-10T12:44:47Z",
+			HTMLURL:   "https://github.com/meain/dotfiles/pull/2#issuecomment-3",
+			IssueURL:  "https://api.github.com/repos/meain/dotfiles/issues/2",
+			User:      GithubUser{Login: "someone", Type: "User"},
+		},
+		GithubComment{
+			Body:      "On an issue we don't know about",
+			CreatedAt: "2021-09-10T12:44:47Z",
+			HTMLURL:   "https://github.com/meain/dotfiles/issues/3#issuecomment-4",
+			IssueURL:  "https://api.github.com/repos/meain/dotfiles/issues/3",
+			User:      GithubUser{Login: "someone", Type: "User"},
+		},
+	}
+
+	table := []struct {
+		name     string
+		rc       RunConfig
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "all",
+			rc:   RunConfig{Repo: "meain/dotfiles"},
+			contains: []string{
+				"<title>meain/dotfiles comments</title>",
+				"<title>[issue-comment]: Crash on start</title>",
+				"<title>[pr-comment]: Add docs</title>",
+				"<guid>https://github.com/meain/dotfiles/issues/1#issuecomment-1</guid>",
+				"<link>https://github.com/meain/dotfiles/pull/2#issuecomment-3</link>",
+				"<category>bug</category>",
+				"&lt;strong&gt;here&lt;/strong&gt;",
+				"Coverage report",
+			},
+			excludes: []string{"issuecomment-4"},
+		},
+		{
+			name:     "filtered",
+			rc:       RunConfig{Repo: "meain/dotfiles", Labels: []string{"bug"}},
+			contains: []string{"Crash on start"},
+			excludes: []string{"Add docs"},
+		},
+		{
+			name:     "nobots",
+			rc:       RunConfig{Repo: "meain/dotfiles", NoBots: true},
+			contains: []string{"Looks good"},
+			excludes: []string{"Coverage report"},
+		},
+		{
+			name:     "author in title",
+			rc:       RunConfig{Repo: "meain/dotfiles", TitleTemplate: "{{.Author}} on #{{.Number}}"},
+			contains: []string{"<title>someone on #2</title>", "<title>meain on #1</title>"},
+		},
+		{
+			name:     "limit",
+			rc:       RunConfig{Repo: "meain/dotfiles", Limit: 1},
+			contains: []string{"Looks good"},
+			excludes: []string{"Crash on start"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := generateCommentRss(issues, comments, tc.rc)
+			if err != nil {
+				t.Fatalf("Unable to generate feed: %s", err)
+			}
+			for _, c := range tc.contains {
+				if !strings.Contains(content, c) {
+					t.Fatalf("Expected %v in feed: %s", c, content)
+				}
+			}
+			for _, c := range tc.excludes {
+				if strings.Contains(content, c) {
+					t.Fatalf("Did not expect %v in feed: %s", c, content)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCommentFeedIssue(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7$").
+		Reply(200).
+		BodyString(`{"number":7,"title":"Crash on start","html_url":"https://github.com/meain/dotfiles/issues/7"}`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/7/comments$").
+		Reply(200).
+		BodyString(`[{"body":"Same here","html_url":"https://github.com/meain/dotfiles/issues/7#issuecomment-1","issue_url":"https://api.github.com/repos/meain/dotfiles/issues/7","created_at":"2021-09-08T12:44:47Z","user":{"login":"meain"}}]`)
+
+	rc := RunConfig{Repo: "meain/dotfiles", Issue: 7, Modes: Modes{true, true, true, true, true, true, true}}
+	content, err := getIssueFeed(rc, time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "<title>meain/dotfiles#7: Crash on start</title>") ||
+		!strings.Contains(content, "<link>https://github.com/meain/dotfiles/issues/7</link>") {
+		t.Fatalf("Invalid title for issue comment feed: %s", content)
+	}
+	if !strings.Contains(content, "<guid>https://github.com/meain/dotfiles/issues/7#issuecomment-1</guid>") {
+		t.Fatalf("Comment missing from feed: %s", content)
+	}
+
+	// served from cache and so no more requests
+	if _, err := getIssueFeed(rc, time.Hour); err != nil {
+		t.Fatalf("Unable to generate feed from cache: %s", err)
+	}
+
+	if _, err := getIssueFeed(RunConfig{Owner: "meain", OwnerType: "org", Comments: true}, time.Hour); err == nil {
+		t.Fatalf("Expected error for comment feed without a repo")
+	}
+}
@@ -5,84 +5,395 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"io"
 
-	"io/fs"
-
 	"github.com/gorilla/feeds"
 )
 
-func makeRequest(repo string) ([]byte, error) {
+// linkUrl extracts the url marked as `rel` (next, prev, first or last)
+// from the `Link` header Github sends on paginated responses. Returns
+// an empty string if there is no such link.
+func linkUrl(link string, rel string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		for _, segment := range segments[1:] {
+			if strings.TrimSpace(segment) == `rel="`+rel+`"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// nextPageUrl extracts the url marked as rel="next" from the `Link`
+// header. Returns an empty string when there are no more pages.
+func nextPageUrl(link string) string {
+	return linkUrl(link, "next")
+}
+
+// RateLimitError is returned when Github has asked us to back off
+// until Reset. No outbound requests are made until then.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limited by Github until " + e.Reset.Format(time.RFC3339)
+}
+
+// Github has separate rate limits for different parts of the API, like
+// search which only allows 30 requests a minute. We keep track of them
+// separately so that running out of one does not block the others.
+var rateLimitedUntil = map[string]time.Time{}
+var rateLimitLock sync.Mutex
+
+// rateLimitResource returns the rate limit bucket Github uses for `url`
+// (core or search), matching X-RateLimit-Resource on the response
+func rateLimitResource(url string) string {
+	if strings.HasPrefix(url, apiUrl+"search/") {
+		return "search"
+	}
+	return "core"
+}
+
+func checkRateLimit(resource string) error {
+	rateLimitLock.Lock()
+	defer rateLimitLock.Unlock()
+
+	if until := rateLimitedUntil[resource]; time.Now().Before(until) {
+		return &RateLimitError{Reset: until}
+	}
+	return nil
+}
+
+func setRateLimit(resource string, reset time.Time) {
+	rateLimitLock.Lock()
+	defer rateLimitLock.Unlock()
+
+	if reset.After(rateLimitedUntil[resource]) {
+		rateLimitedUntil[resource] = reset
+	}
+}
+
+// rateLimitReset figures out till when we should not be making any
+// more requests. Returns zero time if we are not rate limited.
+func rateLimitReset(response *http.Response) time.Time {
+	if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		if err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	if response.Header.Get("X-RateLimit-Remaining") != "0" {
+		if response.StatusCode == http.StatusTooManyRequests {
+			return time.Now().Add(time.Minute)
+		}
+		return time.Time{}
+	}
+
+	reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Now().Add(time.Minute)
+	}
+	return time.Unix(reset, 0)
+}
+
+// secondaryRateLimited checks if this is a 403 from Github's
+// secondary rate limits. These might not come with any of the rate
+// limit headers and are only identifiable from the message.
+func secondaryRateLimited(response *http.Response) bool {
+	if response.StatusCode != http.StatusForbidden {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// errNotModified is returned when Github tells us (via a 304) that
+// the content we have cached is still up to date
+var errNotModified = errors.New("content not modified")
+
+func fetchPage(url string, meta cacheMeta) ([]byte, string, cacheMeta, error) {
+	resource := rateLimitResource(url)
+	if err := checkRateLimit(resource); err != nil {
+		return nil, "", cacheMeta{}, err
+	}
+
 	// do an http get request to the github api. Add auth header if token is present
-	req, err := http.NewRequest("GET", baseUrl+repo+"/issues?state=all", nil)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", cacheMeta{}, err
 	}
 
 	token := os.Getenv("GH_ISSUES_TO_RSS_GITHUB_TOKEN")
 	if token != "" {
-		req.Header.Add("Authorization", "Bearer"+token)
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	// Github does not count 304 responses against the rate limit
+	if meta.ETag != "" {
+		req.Header.Add("If-None-Match", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		req.Header.Add("If-Modified-Since", meta.LastModified)
 	}
 
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", cacheMeta{}, err
 	}
 
-	if response.StatusCode != 200 {
-		return nil, errors.New("unable to fetch data, make sure you have a valid repo")
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		return nil, "", meta, errNotModified
 	}
 
-	defer response.Body.Close()
+	if r := response.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	reset := rateLimitReset(response)
+	if reset.IsZero() && secondaryRateLimited(response) {
+		reset = time.Now().Add(time.Minute)
+	}
+	if !reset.IsZero() {
+		setRateLimit(resource, reset)
+		if response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusTooManyRequests {
+			return nil, "", cacheMeta{}, &RateLimitError{Reset: reset}
+		}
+	}
+
+	if response.StatusCode != 200 {
+		return nil, "", cacheMeta{}, errors.New("unable to fetch data, make sure you have a valid repo")
+	}
 
 	body, err := io.ReadAll(io.Reader(response.Body))
 	if err != nil {
-		return nil, err
+		return nil, "", cacheMeta{}, err
 	}
 
-	return body, nil
+	newMeta := cacheMeta{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	return body, response.Header.Get("Link"), newMeta, nil
 }
 
-func saveBackup(repo string, content []byte) error {
-	path := cacheLocation + "/" + repo
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		err = os.MkdirAll(path, 0755)
+// mergePages combines multiple pages of json arrays into a single array
+func mergePages(pages [][]byte) ([]byte, error) {
+	if len(pages) == 1 {
+		return pages[0], nil
+	}
+
+	merged := []json.RawMessage{}
+	for _, page := range pages {
+		var entries []json.RawMessage
+		if err := json.Unmarshal(page, &entries); err != nil {
+			return nil, err
+		}
+		merged = append(merged, entries...)
+	}
+
+	return json.Marshal(merged)
+}
+
+// fetchPages fetches all the pages (up to `limit`) starting at `url`.
+// If there are more pages than that, it also returns the url of the
+// next page which was left unread, otherwise an empty string. If the
+// content has not changed since the request which returned `meta`, it
+// returns errNotModified.
+func fetchPages(url string, meta cacheMeta, limit int) ([][]byte, cacheMeta, string, error) {
+	var pages [][]byte
+	for url != "" && (len(pages) < limit || len(pages) == 0) {
+		body, link, pageMeta, err := fetchPage(url, meta)
 		if err != nil {
-			fmt.Println("Unable to create directory for caching:", err)
+			return nil, cacheMeta{}, "", err
+		}
+		pages = append(pages, body)
+
+		// The validators of the first page are the ones we can
+		// use to check if anything has changed
+		if len(pages) == 1 {
+			meta = pageMeta
 		}
+
+		url = nextPageUrl(link)
 	}
 
-	err := os.WriteFile(path+"/issues.json", content, fs.FileMode(0644))
+	return pages, meta, url, nil
+}
+
+// fetchLastPages is like fetchPages, but fetches the last `limit`
+// pages rather than the first ones. This is for lists which Github
+// only returns oldest first. Pages are still returned in order.
+func fetchLastPages(url string, meta cacheMeta, limit int) ([][]byte, cacheMeta, error) {
+	first, link, meta, err := fetchPage(url, meta)
 	if err != nil {
-		return err
+		return nil, cacheMeta{}, err
 	}
-	return nil
+
+	url = linkUrl(link, "last")
+	if url == "" {
+		return [][]byte{first}, meta, nil
+	}
+
+	var pages [][]byte
+	for url != "" && len(pages) < limit {
+		body, link, _, err := fetchPage(url, cacheMeta{})
+		if err != nil {
+			return nil, cacheMeta{}, err
+		}
+		pages = append([][]byte{body}, pages...)
+
+		// we already have the first page
+		url = linkUrl(link, "prev")
+		if url != "" && url == linkUrl(link, "first") {
+			if len(pages) < limit {
+				pages = append([][]byte{first}, pages...)
+			}
+			break
+		}
+	}
+
+	return pages, meta, nil
 }
 
-func loadBackup(repo string, timeout time.Duration) ([]byte, error) {
-	path := cacheLocation + "/" + repo + "/issues.json"
+// fetchAll fetches all the pages (up to `limit`) starting at `url` and
+// merges them. If the content has not changed since the request which
+// returned `meta`, it returns errNotModified.
+func fetchAll(url string, meta cacheMeta, limit int) ([]byte, cacheMeta, error) {
+	pages, meta, _, err := fetchPages(url, meta, limit)
+	if err != nil {
+		return nil, cacheMeta{}, err
+	}
 
-	fi, err := os.Stat(path)
+	content, err := mergePages(pages)
 	if err != nil {
-		return nil, err
+		return nil, cacheMeta{}, err
+	}
+	return content, meta, nil
+}
+
+// makeRequest fetches all the issues of a repo which were updated
+// after `since` (all of them if empty). If the content has not changed
+// since the request which returned `meta`, it returns errNotModified.
+func makeRequest(repo string, since string, meta cacheMeta) ([]byte, cacheMeta, error) {
+	// Sorting by updated makes sure that recently closed issues show
+	// up in the first few pages even if they were opened a long time ago.
+	url := baseUrl + repo + "/issues?state=all&sort=updated&per_page=100"
+	if since != "" {
+		url += "&since=" + since
 	}
 
-	if time.Now().Sub(fi.ModTime()) > timeout {
-		return nil, nil
+	pages, meta, next, err := fetchPages(url, meta, maxPages)
+	if err != nil {
+		return nil, cacheMeta{}, err
+	}
+
+	// When refreshing, we need everything updated since the last
+	// refresh. The next refresh starts after the latest update and
+	// so anything we skip now would never be fetched.
+	for since != "" && next != "" {
+		var more [][]byte
+		more, _, next, err = fetchPages(next, cacheMeta{}, maxPages)
+		if err != nil {
+			return nil, cacheMeta{}, err
+		}
+		pages = append(pages, more...)
 	}
 
-	b, err := os.ReadFile(path)
+	content, err := mergePages(pages)
+	if err != nil {
+		return nil, cacheMeta{}, err
+	}
+	return content, meta, nil
+}
+
+// makeEventsRequest fetches the latest issue events (closed, reopened
+// etc) across all the issues of a repo
+func makeEventsRequest(repo string, meta cacheMeta) ([]byte, cacheMeta, error) {
+	return fetchAll(baseUrl+repo+"/issues/events?per_page=100", meta, maxPages)
+}
+
+func saveBackup(repo string, content []byte) error {
+	return cache.Save(repo+"/issues.json", content)
+}
+
+func loadBackup(repo string, timeout time.Duration) ([]byte, error) {
+	b, saved, err := cache.Load(repo + "/issues.json")
 	if err != nil {
 		return nil, err
 	}
 
+	if time.Now().Sub(saved) > timeout {
+		return nil, nil
+	}
+
 	return b, nil
 }
 
+// loadStaleBackup loads the backup ignoring how old it is
+func loadStaleBackup(repo string) ([]byte, error) {
+	b, _, err := cache.Load(repo + "/issues.json")
+	return b, err
+}
+
+// touchBackup marks the existing backup as fresh
+func touchBackup(repo string) error {
+	return cache.Touch(repo + "/issues.json")
+}
+
+// saveMeta stores the validators Github gave us for some cached
+// content so that we can make conditional requests later
+func saveMeta(key string, meta cacheMeta) error {
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return cache.Save(key, content)
+}
+
+func loadMeta(key string) (cacheMeta, error) {
+	meta := cacheMeta{}
+	content, _, err := cache.Load(key)
+	if err != nil {
+		return meta, err
+	}
+
+	err = json.Unmarshal(content, &meta)
+	return meta, err
+}
+
+// loadEvents loads the issue events we have cached for a repo. These
+// are only refreshed along with issues and so we don't care how old
+// they are.
+func loadEvents(repo string) []GithubIssueEvent {
+	var events []GithubIssueEvent
+	content, _, err := cache.Load(repo + "/events.json")
+	if err != nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(content, &events); err != nil {
+		fmt.Println("Unable to parse cached events for "+repo+":", err)
+		return nil
+	}
+	return events
+}
+
 func isIn(item string, items []string) bool {
 	for _, i := range items {
 		if i == item {
@@ -92,6 +403,89 @@ func isIn(item string, items []string) bool {
 	return false
 }
 
+// itemGuid returns a stable identifier for a feed item. Every event
+// (open, closed...) on an issue gets its own id so that readers don't
+// mix them up, and the id does not change when the issue is edited.
+func itemGuid(repo string, number int64, event string) string {
+	return "https://github.com/" + repo + "/issues/" + strconv.FormatInt(number, 10) + "#" + event
+}
+
+// eventEnabled checks if we should show an event (open, closed,
+// reopened or merged) on an issue or pr based on the modes
+func eventEnabled(modes Modes, entryType string, event string) bool {
+	switch entryType + "-" + event {
+	case "issue-open":
+		return modes.IssueOpen
+	case "issue-closed":
+		return modes.IssuesClosed
+	case "issue-reopened":
+		return modes.IssueReopened
+	case "pr-open":
+		return modes.PROpen
+	case "pr-closed":
+		return modes.PRClosed
+	case "pr-merged":
+		return modes.PRMerged
+	case "pr-reopened":
+		return modes.PRReopened
+	}
+	return false
+}
+
+type issueTransition struct {
+	Event string // closed, reopened or merged
+	Id    string // unique among the transitions of an issue
+	Time  time.Time
+}
+
+// issueTransitions returns all the times an issue was closed, reopened
+// or merged in the order in which they happened. We use the events
+// attached to the issue, but fall back to ClosedAt in case the events
+// of the latest close are not available.
+func issueTransitions(entry GithubIssue, entryType string) []issueTransition {
+	events := make([]GithubIssueEvent, len(entry.Events))
+	copy(events, entry.Events)
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].CreatedAt == events[j].CreatedAt {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].CreatedAt < events[j].CreatedAt
+	})
+
+	// Ids are based on when the transition happened rather than on how
+	// many came before it, as the events only go back so far and the
+	// first close we know about might not be the first one. The close
+	// event and ClosedAt have the same time and so get the same id.
+	var transitions []issueTransition
+	add := func(event string, at string) {
+		t, _ := time.Parse("2006-01-02T15:04:05Z07:00", at)
+		id := event + "-" + t.UTC().Format(time.RFC3339)
+		transitions = append(transitions, issueTransition{event, id, t})
+	}
+
+	for _, event := range events {
+		if event.Event == "closed" || event.Event == "reopened" {
+			add(event.Event, event.CreatedAt)
+		}
+	}
+
+	if entry.State == "closed" && (len(transitions) == 0 || transitions[len(transitions)-1].Event != "closed") {
+		add("closed", entry.ClosedAt)
+	}
+
+	// A merged pr cannot be reopened, which makes its last close the merge
+	if entryType == "pr" && entry.PullRequest.MergedAt != "" && len(transitions) > 0 {
+		last := &transitions[len(transitions)-1]
+		if last.Event == "closed" {
+			last.Event = "merged"
+			last.Id = "merged"
+			last.Time, _ = time.Parse("2006-01-02T15:04:05Z07:00", entry.PullRequest.MergedAt)
+		}
+	}
+
+	return transitions
+}
+
 func generateRss(data []GithubIssue, rc RunConfig) (string, error) {
 	now := time.Now()
 	feed := &feeds.Feed{
@@ -100,144 +494,606 @@ func generateRss(data []GithubIssue, rc RunConfig) (string, error) {
 		Created: now,
 	}
 
+	titleTmpl := rc.TitleTemplate
+	if titleTmpl == "" {
+		titleTmpl = titleTemplate
+	}
+
+	// combined feeds need the repo in the title to make sense of things
+	combined := len(rc.Repos) > 0 || rc.Search != ""
+	if combined {
+		feed.Title = strings.Join(rc.Repos, ", ")
+		feed.Link = &feeds.Link{Href: "https://github.com"}
+		if rc.Owner != "" {
+			feed.Title = rc.Owner
+			feed.Link = &feeds.Link{Href: "https://github.com/" + rc.Owner}
+		}
+		if rc.Search != "" {
+			feed.Title = "Search: " + rc.Search
+			feed.Link = &feeds.Link{Href: "https://github.com/search?type=issues&q=" + url.QueryEscape(rc.Search)}
+		}
+		// unless a template was picked for this feed or the server
+		if rc.TitleTemplate == "" && titleTemplate == "default" {
+			titleTmpl = "repo"
+		}
+	}
+	tmpl, err := parseTitleTemplate(titleTmpl)
+	if err != nil {
+		return "", err
+	}
+
 	var items []*feeds.Item
-	fdata := filterIssues(data, rc)
+	categories := itemCategories{}
+	fdata, err := filterIssues(data, rc)
+	if err != nil {
+		return "", err
+	}
 
 	for _, entry := range fdata {
 		entryType := "issue"
 		if entry.PullRequest.URL != "" {
 			entryType = "pr"
 		}
+		body := renderBody(entry.Body, entry.HTMLURL, rc.BodyFormat) + issueFooter(entry, rc.BodyFormat)
+		var labels []string
+		for _, label := range entry.Labels {
+			labels = append(labels, label.Name)
+		}
+
 		createTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", entry.CreatedAt)
-		closeTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", entry.ClosedAt)
-
-		if entry.State == "closed" {
-			if !((entryType == "pr" && !rc.Modes.PRClosed) || (entryType == "issue" && !rc.Modes.IssuesClosed)) {
-				items = append(items, &feeds.Item{
-					Title:       "[" + entryType + "-" + "closed" + "]: " + entry.Title,
-					Link:        &feeds.Link{Href: entry.HTMLURL},
-					Description: strings.ReplaceAll(entry.Body, "\n", "<br>"),
-					Content:     strings.ReplaceAll(entry.Body, "\n", "<br>"),
-					Author:      &feeds.Author{Name: entry.User.Login},
-					Created:     closeTime,
-				})
+
+		repo := entry.Repo
+		if repo == "" {
+			repo = rc.Repo
+		}
+
+		// latest transitions first, with the open at the very end
+		transitions := issueTransitions(entry, entryType)
+		for i := len(transitions) - 1; i >= 0; i-- {
+			transition := transitions[i]
+			if !eventEnabled(rc.Modes, entryType, transition.Event) {
+				continue
+			}
+			title, err := renderTitle(tmpl, titleData{repo, entry.Number, entryType, transition.Event, entry.Title, labels, entry.User.Login})
+			if err != nil {
+				return "", err
+			}
+			item := &feeds.Item{
+				Title:       title,
+				Link:        &feeds.Link{Href: entry.HTMLURL},
+				Description: body,
+				Content:     body,
+				Author:      &feeds.Author{Name: entry.User.Login},
+				Id:          itemGuid(repo, entry.Number, transition.Id),
+				Created:     transition.Time,
 			}
+			items = append(items, item)
+			categories[item] = labels
 		}
-		if (entryType == "pr" && !rc.Modes.PROpen) || (entryType == "issue" && !rc.Modes.IssueOpen) {
+
+		if !eventEnabled(rc.Modes, entryType, "open") {
 			continue
 		}
-		items = append(items, &feeds.Item{
-			Title:       "[" + entryType + "-" + "open" + "]: " + entry.Title,
+		title, err := renderTitle(tmpl, titleData{repo, entry.Number, entryType, "open", entry.Title, labels, entry.User.Login})
+		if err != nil {
+			return "", err
+		}
+		item := &feeds.Item{
+			Title:       title,
 			Link:        &feeds.Link{Href: entry.HTMLURL},
-			Description: strings.ReplaceAll(entry.Body, "\n", "<br>"),
-			Content:     strings.ReplaceAll(entry.Body, "\n", "<br>"),
+			Description: body,
+			Content:     body,
 			Author:      &feeds.Author{Name: entry.User.Login},
+			Id:          itemGuid(repo, entry.Number, "open"),
 			Created:     createTime,
-		})
+		}
+		items = append(items, item)
+		categories[item] = labels
+
+	}
 
+	if combined {
+		sortItems(items)
 	}
-	feed.Items = items
 
-	rss, err := feed.ToRss()
+	feed.Items, err = limitItems(items, rc, now)
 	if err != nil {
 		return "", err
 	}
-	return rss, nil
+
+	return renderFeed(feed, categories, rc.Format)
 }
 
-func getData(repo string, cacheTimeout time.Duration) ([]byte, error) {
-	content, err := loadBackup(repo, cacheTimeout)
-	if err != nil || content == nil {
-		fmt.Println("No cache found for " + repo + ", fetching from Github")
-		resp, err := makeRequest(repo)
-		if err != nil {
+// issueKey is the minimal information needed to merge issue lists
+type issueKey struct {
+	Number    int64  `json:"number"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// latestUpdate returns the most recent updated_at among the issues
+// (or comments) in `content`. This is what we use as `since` for the
+// next refresh.
+func latestUpdate(content []byte) string {
+	var issues []issueKey
+	if err := json.Unmarshal(content, &issues); err != nil {
+		return ""
+	}
+
+	latest := ""
+	for _, issue := range issues {
+		// timestamps from Github are all in UTC and so can be compared as strings
+		if issue.UpdatedAt > latest {
+			latest = issue.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// mergeIssues merges the issues in `updates` into the ones in
+// `stored`, keyed by issue number. The result is sorted with the most
+// recently updated issues first, similar to what Github returns.
+func mergeIssues(stored []byte, updates []byte) ([]byte, error) {
+	var storedIssues, updatedIssues []json.RawMessage
+	if err := json.Unmarshal(stored, &storedIssues); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(updates, &updatedIssues); err != nil {
+		return nil, err
+	}
+
+	keys := map[int64]issueKey{}
+	issues := map[int64]json.RawMessage{}
+	for _, raw := range append(storedIssues, updatedIssues...) {
+		var key issueKey
+		if err := json.Unmarshal(raw, &key); err != nil {
 			return nil, err
 		}
-		err = saveBackup(repo, resp)
-		if err != nil {
-			fmt.Println("Unable to save backup:", err)
+		keys[key.Number] = key
+		issues[key.Number] = raw
+	}
+
+	var numbers []int64
+	for number := range issues {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		ki, kj := keys[numbers[i]], keys[numbers[j]]
+		if ki.UpdatedAt == kj.UpdatedAt {
+			return ki.Number > kj.Number
 		}
-		return resp, nil
+		return ki.UpdatedAt > kj.UpdatedAt
+	})
+
+	merged := []json.RawMessage{}
+	for _, number := range numbers {
+		merged = append(merged, issues[number])
 	}
-	return content, nil
+	return json.Marshal(merged)
 }
 
-func getIssueFeed(rc RunConfig, cacheTimeout time.Duration) (string, error) {
-	content, err := getData(rc.Repo, cacheTimeout)
+// Issue events which we care about, we don't store the rest
+var timelineEvents = []string{"closed", "reopened", "merged"}
+
+// mergeEvents merges the events in `updates` into the ones in
+// `stored`, keyed by event id, with the latest ones first
+func mergeEvents(stored []byte, updates []byte) ([]byte, error) {
+	var storedEvents, updatedEvents []json.RawMessage
+	if err := json.Unmarshal(stored, &storedEvents); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(updates, &updatedEvents); err != nil {
+		return nil, err
+	}
+
+	events := map[int64]json.RawMessage{}
+	for _, raw := range append(storedEvents, updatedEvents...) {
+		var key struct {
+			ID    int64  `json:"id"`
+			Event string `json:"event"`
+		}
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, err
+		}
+		if isIn(key.Event, timelineEvents) {
+			events[key.ID] = raw
+		}
+	}
+
+	var ids []int64
+	for id := range events {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+
+	merged := []json.RawMessage{}
+	for _, id := range ids {
+		merged = append(merged, events[id])
+	}
+	return json.Marshal(merged)
+}
+
+// refreshEvents fetches the latest issue events of a repo and merges
+// them into the ones we have cached
+func refreshEvents(repo string) error {
+	stored, _, _ := cache.Load(repo + "/events.json")
+	meta := cacheMeta{}
+	if stored != nil {
+		meta, _ = loadMeta(repo + "/events-meta.json")
+	}
+
+	resp, meta, err := makeEventsRequest(repo, meta)
+	if errors.Is(err, errNotModified) {
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	data := []GithubIssue{}
-	if err := json.Unmarshal(content, &data); err != nil {
-		return "", err
+	if stored == nil {
+		stored = []byte("[]")
+	}
+	resp, err = mergeEvents(stored, resp)
+	if err != nil {
+		return err
 	}
 
-	rss, err := generateRss(data, rc)
+	err = cache.Save(repo+"/events.json", resp)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return rss, nil
+	return saveMeta(repo+"/events-meta.json", meta)
 }
 
-func filterIssues(issues []GithubIssue, rc RunConfig) []GithubIssue {
-	var fi []GithubIssue
+type inflightCall struct {
+	wg      sync.WaitGroup
+	content []byte
+	err     error
+}
 
-	for _, issue := range issues {
-		var issueLabels []string
-		for _, i := range issue.Labels {
-			issueLabels = append(issueLabels, i.Name)
+var inflight = map[string]*inflightCall{}
+var inflightLock sync.Mutex
+
+// coalesce makes sure that only one `fn` is running for a given key.
+// Callers which come in while one is in flight wait for it and get
+// its result instead of starting their own.
+func coalesce(key string, fn func() ([]byte, error)) ([]byte, error) {
+	inflightLock.Lock()
+	if call, ok := inflight[key]; ok {
+		inflightLock.Unlock()
+		call.wg.Wait()
+		return call.content, call.err
+	}
+
+	call := &inflightCall{}
+	call.wg.Add(1)
+	inflight[key] = call
+	inflightLock.Unlock()
+
+	call.content, call.err = fn()
+	call.wg.Done()
+
+	inflightLock.Lock()
+	delete(inflight, key)
+	inflightLock.Unlock()
+
+	return call.content, call.err
+}
+
+// refreshData fetches the latest data for a repo from Github, merges
+// it into what we have cached and saves it. Concurrent refreshes of
+// the same repo result in a single fetch.
+func refreshData(repo string) ([]byte, error) {
+	return coalesce(repo+"/issues.json", func() ([]byte, error) {
+		return fetchData(repo)
+	})
+}
+
+func fetchData(repo string) ([]byte, error) {
+	// Only make a conditional request if we have something to
+	// fall back to in case Github says nothing has changed. If we
+	// have something, we only need to fetch what changed after it.
+	stale, _ := loadStaleBackup(repo)
+	meta := cacheMeta{}
+	since := ""
+	if stale != nil {
+		meta, _ = loadMeta(repo + "/meta.json")
+		since = latestUpdate(stale)
+	}
+
+	resp, meta, err := makeRequest(repo, since, meta)
+	if errors.Is(err, errNotModified) {
+		err = touchBackup(repo)
+		if err != nil {
+			fmt.Println("Unable to refresh backup:", err)
 		}
+		return stale, nil
+	}
 
-		bk := false
-		for _, label := range rc.NotLabels {
-			if isIn(label, issueLabels) {
-				bk = true
-				break
-			}
+	var rle *RateLimitError
+	if errors.As(err, &rle) && stale != nil {
+		fmt.Println("Rate limited, using stale cache for " + repo)
+		return stale, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if since != "" {
+		merged, err := mergeIssues(stale, resp)
+		if err != nil {
+			return nil, err
 		}
+		resp = merged
+	}
+
+	// Every new event on an issue also updates it, so we only have to
+	// look for new events when issues have changed
+	err = refreshEvents(repo)
+	if err != nil {
+		fmt.Println("Unable to fetch events for "+repo+":", err)
+	}
 
-		if bk {
+	err = saveBackup(repo, resp)
+	if err != nil {
+		fmt.Println("Unable to save backup:", err)
+		return resp, nil
+	}
+	err = saveMeta(repo+"/meta.json", meta)
+	if err != nil {
+		fmt.Println("Unable to save cache metadata:", err)
+	}
+	return resp, nil
+}
+
+// Repos which have been requested recently along with when they were
+// last requested. Used by the background refresher.
+var recentRepos = map[string]time.Time{}
+var recentReposLock sync.Mutex
+
+// How long after the last request we keep refreshing a repo in background
+var recentWindow = 24 * time.Hour
+
+func markRequested(repo string) {
+	recentReposLock.Lock()
+	defer recentReposLock.Unlock()
+	recentRepos[repo] = time.Now()
+}
+
+// refreshRecent refreshes the cache for all the repos requested within
+// recentWindow and forgets about the rest
+func refreshRecent() {
+	recentReposLock.Lock()
+	var repos []string
+	for repo, requested := range recentRepos {
+		if time.Since(requested) > recentWindow {
+			delete(recentRepos, repo)
 			continue
 		}
+		repos = append(repos, repo)
+	}
+	recentReposLock.Unlock()
 
-		for _, user := range rc.NotUsers {
-			if issue.User.Login == user {
-				bk = true
-				break
-			}
+	for _, repo := range repos {
+		_, err := refreshData(repo)
+		if err != nil {
+			fmt.Println("Unable to refresh "+repo+":", err)
 		}
+	}
+}
 
-		if bk {
-			continue
+// startRefresher proactively refreshes recently requested repos every
+// `interval` so that feed readers rarely have to wait on Github
+func startRefresher(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			refreshRecent()
+		}
+	}()
+}
+
+func getData(repo string, cacheTimeout time.Duration) ([]byte, error) {
+	markRequested(repo)
+
+	content, err := loadBackup(repo, cacheTimeout)
+	if err == nil && content != nil {
+		return content, nil
+	}
+
+	if staleWhileRevalidate {
+		stale, _ := loadStaleBackup(repo)
+		if stale != nil {
+			fmt.Println("Cache expired for " + repo + ", refreshing in background")
+			go func() {
+				_, err := refreshData(repo)
+				if err != nil {
+					fmt.Println("Unable to refresh "+repo+":", err)
+				}
+			}()
+			return stale, nil
+		}
+	}
+
+	fmt.Println("No cache found for " + repo + ", fetching from Github")
+	return refreshData(repo)
+}
+
+// getCached returns the content cached under `key` if it is fresh and
+// otherwise refreshes it using `fetch`, making a conditional request if
+// there is something cached. This is used for things other than issues
+// of a repo, like repo lists and search results.
+func getCached(key string, cacheTimeout time.Duration, fetch func(meta cacheMeta) ([]byte, cacheMeta, error)) ([]byte, error) {
+	content, saved, err := cache.Load(key)
+	if err == nil && time.Now().Sub(saved) <= cacheTimeout {
+		return content, nil
+	}
+
+	return coalesce(key, func() ([]byte, error) {
+		stale, _, _ := cache.Load(key)
+		meta := cacheMeta{}
+		if stale != nil {
+			meta, _ = loadMeta(key + ".meta")
 		}
 
-		for _, label := range rc.Labels {
-			if !isIn(label, issueLabels) {
-				bk = true
-				break
+		resp, meta, err := fetch(meta)
+		if errors.Is(err, errNotModified) {
+			err = cache.Touch(key)
+			if err != nil {
+				fmt.Println("Unable to refresh cache:", err)
 			}
+			return stale, nil
 		}
 
-		if bk {
-			continue
+		var rle *RateLimitError
+		if errors.As(err, &rle) && stale != nil {
+			fmt.Println("Rate limited, using stale cache for " + key)
+			return stale, nil
 		}
 
-		bk = len(rc.Users) != 0
+		if err != nil {
+			return nil, err
+		}
 
-		for _, user := range rc.Users {
-			if issue.User.Login == user {
-				bk = false
-				break
-			}
+		err = cache.Save(key, resp)
+		if err != nil {
+			fmt.Println("Unable to save cache:", err)
+			return resp, nil
 		}
+		err = saveMeta(key+".meta", meta)
+		if err != nil {
+			fmt.Println("Unable to save cache metadata:", err)
+		}
+		return resp, nil
+	})
+}
+
+// attachEvents adds the events of each issue to it
+func attachEvents(issues []GithubIssue, events []GithubIssueEvent) {
+	byNumber := map[int64][]GithubIssueEvent{}
+	for _, event := range events {
+		byNumber[event.Issue.Number] = append(byNumber[event.Issue.Number], event)
+	}
+
+	for i := range issues {
+		issues[i].Events = byNumber[issues[i].Number]
+	}
+}
 
-		if bk {
+// getIssues returns the issues (along with their events) of a repo
+func getIssues(repo string, cacheTimeout time.Duration) ([]GithubIssue, error) {
+	content, err := getData(repo, cacheTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []GithubIssue{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	attachEvents(data, loadEvents(repo))
+	for i := range data {
+		data[i].Repo = repo
+	}
+
+	return data, nil
+}
+
+// Max number of repos to fetch at once for combined feeds
+var multiConcurrency = 8
+
+// getMultiIssues fetches the issues of all the repos in parallel. Repos
+// which fail are skipped so that one bad repo does not take down the
+// whole feed, unless all of them fail.
+func getMultiIssues(repos []string, cacheTimeout time.Duration) ([]GithubIssue, error) {
+	type result struct {
+		issues []GithubIssue
+		err    error
+	}
+
+	results := make([]result, len(repos))
+	sem := make(chan struct{}, multiConcurrency)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(i int, repo string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i].issues, results[i].err = getIssues(repo, cacheTimeout)
+		}(i, repo)
+	}
+	wg.Wait()
+
+	var data []GithubIssue
+	var firstErr error
+	for i, r := range results {
+		if r.err != nil {
+			fmt.Println("Unable to fetch issues for", repos[i], ":", r.err)
+			if firstErr == nil {
+				firstErr = r.err
+			}
 			continue
 		}
+		data = append(data, r.issues...)
+	}
+
+	if data == nil && firstErr != nil {
+		return nil, firstErr
+	}
+	return data, nil
+}
 
-		fi = append(fi, issue)
+func getIssueFeed(rc RunConfig, cacheTimeout time.Duration) (string, error) {
+	if rc.Releases {
+		return getReleaseFeed(rc, cacheTimeout)
+	}
+	if rc.Comments || rc.Issue != 0 {
+		return getCommentFeed(rc, cacheTimeout)
+	}
+
+	if rc.Owner != "" {
+		repos, err := getRepoList(rc.OwnerType, rc.Owner, cacheTimeout)
+		if err != nil {
+			return "", err
+		}
+		rc.Repos = selectRepos(repos, rc)
+		if len(rc.Repos) == 0 {
+			return "", errors.New("no repos found for " + rc.OwnerType + " " + rc.Owner)
+		}
+	}
+
+	var data []GithubIssue
+	var err error
+	if rc.Search != "" {
+		data, err = getSearchIssues(rc.Search, cacheTimeout)
+	} else if len(rc.Repos) > 0 {
+		data, err = getMultiIssues(rc.Repos, cacheTimeout)
+	} else {
+		data, err = getIssues(rc.Repo, cacheTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	rss, err := generateRss(data, rc)
+	if err != nil {
+		return "", err
+	}
+	return rss, nil
+}
+
+func filterIssues(issues []GithubIssue, rc RunConfig) ([]GithubIssue, error) {
+	filter, err := compileFilter(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	var fi []GithubIssue
+	for _, issue := range issues {
+		if filter.match(issue) {
+			fi = append(fi, issue)
+		}
 	}
 
-	return fi
+	return fi, nil
 }
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"os"
 
+	"github.com/google/go-cmp/cmp"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -18,7 +22,7 @@ func TestMakeRequest(t *testing.T) {
 		Get("/issues").
 		Reply(200).BodyString("mango")
 
-	content, err := makeRequest("meain/dotfiles")
+	content, _, err := makeRequest("meain/dotfiles", "", cacheMeta{})
 	if err != nil {
 		t.Fatalf("Unable to fetch star count")
 	}
@@ -27,6 +31,392 @@ func TestMakeRequest(t *testing.T) {
 	}
 }
 
+func TestMakeRequestPagination(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchParam("page", "2").
+		Reply(200).BodyString(`[{"number":2}]`)
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(200).
+		SetHeader("Link", `<https://api.github.com/repos/meain/dotfiles/issues?page=2>; rel="next", <https://api.github.com/repos/meain/dotfiles/issues?page=2>; rel="last"`).
+		BodyString(`[{"number":1}]`)
+
+	content, _, err := makeRequest("meain/dotfiles", "", cacheMeta{})
+	if err != nil {
+		t.Fatalf("Unable to fetch paginated data: %s", err)
+	}
+	if string(content) != `[{"number":1},{"number":2}]` {
+		t.Fatalf("Pages were not merged. got %v", string(content))
+	}
+}
+
+func TestNextPageUrl(t *testing.T) {
+	table := []struct {
+		link string
+		next string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=3>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, "https://api.github.com/x?page=3"},
+		{`<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=1>; rel="first"`, ""},
+	}
+
+	for _, tc := range table {
+		if got := nextPageUrl(tc.link); got != tc.next {
+			t.Fatalf("expected %v, got %v", tc.next, got)
+		}
+	}
+}
+
+func TestMakeRequestNotModified(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchHeader("If-None-Match", `"abc"`).
+		Reply(304)
+
+	_, _, err := makeRequest("meain/dotfiles", "", cacheMeta{ETag: `"abc"`})
+	if !errors.Is(err, errNotModified) {
+		t.Fatalf("Expected not modified error, got %v", err)
+	}
+}
+
+func TestGetDataNotModified(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		log.Fatal("Unable to create temp directory:", err)
+	}
+	cacheLocation = dir
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(200).
+		SetHeader("ETag", `"abc"`).
+		BodyString("[]")
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchHeader("If-None-Match", `"abc"`).
+		Reply(304)
+
+	_, err = getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Unable to fetch data: %s", err)
+	}
+	content, err := getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Unable to fetch data on 304: %s", err)
+	}
+	if string(content) != "[]" {
+		t.Fatalf("Cached content not reused, got %v", string(content))
+	}
+	if !gock.IsDone() {
+		t.Fatalf("Conditional request was not made")
+	}
+}
+
+func TestMakeRequestRateLimited(t *testing.T) {
+	defer func() { rateLimitedUntil = map[string]time.Time{} }()
+
+	reset := time.Now().Add(time.Hour).Unix()
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(403).
+		SetHeader("X-RateLimit-Remaining", "0").
+		SetHeader("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+
+	_, _, err := makeRequest("meain/dotfiles", "", cacheMeta{})
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected rate limit error, got %v", err)
+	}
+	if rle.Reset.Unix() != reset {
+		t.Fatalf("Invalid reset time, expected %v, got %v", reset, rle.Reset.Unix())
+	}
+
+	// should not even try to make a request till reset
+	_, _, err = makeRequest("meain/dotfiles", "", cacheMeta{})
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected rate limit error without request, got %v", err)
+	}
+}
+
+func TestMakeRequestSecondaryRateLimited(t *testing.T) {
+	defer func() { rateLimitedUntil = map[string]time.Time{} }()
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(403).
+		SetHeader("X-RateLimit-Remaining", "4000").
+		BodyString(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`)
+
+	_, _, err := makeRequest("meain/dotfiles", "", cacheMeta{})
+	var rle *RateLimitError
+	if !errors.As(err, &rle) {
+		t.Fatalf("Expected rate limit error, got %v", err)
+	}
+	if rle.Reset.Before(time.Now().Add(59 * time.Second)) {
+		t.Fatalf("Expected to back off for at least a minute, got %v", rle.Reset)
+	}
+
+	// any other 403 is still an invalid repo
+	rateLimitedUntil = map[string]time.Time{}
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(403).
+		SetHeader("X-RateLimit-Remaining", "4000").
+		BodyString(`{"message":"Repository access blocked"}`)
+
+	_, _, err = makeRequest("meain/dotfiles", "", cacheMeta{})
+	if err == nil || errors.As(err, &rle) {
+		t.Fatalf("Expected invalid repo error, got %v", err)
+	}
+}
+
+func TestGetDataRateLimitedUsesStale(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		log.Fatal("Unable to create temp directory:", err)
+	}
+	cacheLocation = dir
+
+	err = saveBackup("meain/dotfiles", []byte("[]"))
+	if err != nil {
+		t.Fatalf("Unable to save backup file")
+	}
+
+	rateLimitedUntil["core"] = time.Now().Add(time.Hour)
+	defer func() { rateLimitedUntil = map[string]time.Time{} }()
+
+	content, err := getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Stale data was not used when rate limited: %s", err)
+	}
+	if string(content) != "[]" {
+		t.Fatalf("Invalid stale content, got %v", string(content))
+	}
+}
+
+func TestMergeIssues(t *testing.T) {
+	stored := `[{"number":2,"updated_at":"2021-09-08T12:44:47Z","title":"old"},{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`
+	updates := `[{"number":3,"updated_at":"2021-09-10T12:44:47Z"},{"number":2,"updated_at":"2021-09-09T12:44:47Z","title":"new"}]`
+
+	merged, err := mergeIssues([]byte(stored), []byte(updates))
+	if err != nil {
+		t.Fatalf("Unable to merge issues: %s", err)
+	}
+
+	expected := `[{"number":3,"updated_at":"2021-09-10T12:44:47Z"},{"number":2,"updated_at":"2021-09-09T12:44:47Z","title":"new"},{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`
+	if string(merged) != expected {
+		t.Fatalf("Invalid merge, expected %v, got %v", expected, string(merged))
+	}
+	if latestUpdate(merged) != "2021-09-10T12:44:47Z" {
+		t.Fatalf("Invalid latest update, got %v", latestUpdate(merged))
+	}
+}
+
+func TestGetDataIncremental(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		log.Fatal("Unable to create temp directory:", err)
+	}
+	cacheLocation = dir
+
+	err = saveBackup("meain/dotfiles", []byte(`[{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`))
+	if err != nil {
+		t.Fatalf("Unable to save backup file")
+	}
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchParam("since", "2021-09-01T12:44:47Z").
+		Reply(200).
+		BodyString(`[{"number":2,"updated_at":"2021-09-02T12:44:47Z"}]`)
+
+	content, err := getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Unable to fetch data: %s", err)
+	}
+
+	expected := `[{"number":2,"updated_at":"2021-09-02T12:44:47Z"},{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`
+	if string(content) != expected {
+		t.Fatalf("Issues were not merged, expected %v, got %v", expected, string(content))
+	}
+}
+
+func TestGetDataIncrementalBeyondMaxPages(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		log.Fatal("Unable to create temp directory:", err)
+	}
+	cacheLocation = dir
+
+	maxPagesBackup := maxPages
+	defer func() { maxPages = maxPagesBackup }()
+	maxPages = 1
+
+	err = saveBackup("meain/dotfiles", []byte(`[{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`))
+	if err != nil {
+		t.Fatalf("Unable to save backup file")
+	}
+
+	// more updates since the last refresh than maxPages can hold
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchParam("since", "2021-09-01T12:44:47Z").
+		MatchParam("page", "2").
+		Reply(200).
+		BodyString(`[{"number":2,"updated_at":"2021-09-02T12:44:47Z"}]`)
+	gock.New("https://api.github.com").
+		Get("/issues").
+		MatchParam("since", "2021-09-01T12:44:47Z").
+		Reply(200).
+		SetHeader("Link", `<https://api.github.com/repos/meain/dotfiles/issues?since=2021-09-01T12:44:47Z&page=2>; rel="next"`).
+		BodyString(`[{"number":3,"updated_at":"2021-09-03T12:44:47Z"}]`)
+
+	content, err := getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Unable to fetch data: %s", err)
+	}
+
+	expected := `[{"number":3,"updated_at":"2021-09-03T12:44:47Z"},{"number":2,"updated_at":"2021-09-02T12:44:47Z"},{"number":1,"updated_at":"2021-09-01T12:44:47Z"}]`
+	if string(content) != expected {
+		t.Fatalf("Not all updates were fetched, expected %v, got %v", expected, string(content))
+	}
+}
+
+func TestFetchPagesTruncated(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(200).
+		SetHeader("Link", `<https://api.github.com/repos/meain/dotfiles/issues?page=2>; rel="next"`).
+		BodyString(`[{"number":1}]`)
+
+	pages, _, next, err := fetchPages("https://api.github.com/repos/meain/dotfiles/issues", cacheMeta{}, 1)
+	if err != nil {
+		t.Fatalf("Unable to fetch pages: %s", err)
+	}
+	if len(pages) != 1 || next != "https://api.github.com/repos/meain/dotfiles/issues?page=2" {
+		t.Fatalf("Truncation not reported, got %v pages and next %v", len(pages), next)
+	}
+}
+
+func TestGetDataStaleWhileRevalidate(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	staleWhileRevalidate = true
+	defer func() { staleWhileRevalidate = false }()
+
+	err := saveBackup("meain/dotfiles", []byte("[]"))
+	if err != nil {
+		t.Fatalf("Unable to save backup file")
+	}
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(200).
+		BodyString(`[{"number":1}]`)
+
+	content, err := getData("meain/dotfiles", 0)
+	if err != nil {
+		t.Fatalf("Unable to fetch data: %s", err)
+	}
+	if string(content) != "[]" {
+		t.Fatalf("Stale content not served, got %v", string(content))
+	}
+
+	for i := 0; i < 100; i++ {
+		content, _ = loadStaleBackup("meain/dotfiles")
+		if string(content) != "[]" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(content) != `[{"number":1}]` {
+		t.Fatalf("Cache not refreshed in background, got %v", string(content))
+	}
+}
+
+func TestRefreshDataCoalesced(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/issues").
+		Reply(200).
+		Delay(100 * time.Millisecond).
+		BodyString(`[{"number":1}]`)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := refreshData("meain/dotfiles")
+			if err == nil && string(content) != `[{"number":1}]` {
+				err = errors.New("invalid content " + string(content))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent fetches were not coalesced: %s", err)
+		}
+	}
+}
+
+func TestRefreshRecent(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	recentRepos = map[string]time.Time{
+		"meain/dotfiles":  time.Now(),
+		"meain/old-stuff": time.Now().Add(-2 * recentWindow),
+	}
+	defer func() { recentRepos = map[string]time.Time{} }()
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues").
+		Reply(200).
+		BodyString(`[{"number":1}]`)
+
+	refreshRecent()
+
+	content, _ := loadStaleBackup("meain/dotfiles")
+	if string(content) != `[{"number":1}]` {
+		t.Fatalf("Recently requested repo not refreshed, got %v", string(content))
+	}
+	if _, ok := recentRepos["meain/old-stuff"]; ok {
+		t.Fatalf("Old repo should no longer be tracked")
+	}
+}
+
 func TestBackup(t *testing.T) {
 	cacheLocationBackup := cacheLocation
 	defer func() { cacheLocation = cacheLocationBackup }()
@@ -53,8 +443,9 @@ func TestRssGenerationSimple(t *testing.T) {
 	rssContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
   </channel>
@@ -62,13 +453,14 @@ func TestRssGenerationSimple(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
 		},
 	}
 
-	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true}})
+	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}})
 	if err != nil {
 		t.Fatalf("Unable to save backup file")
 	}
@@ -82,22 +474,25 @@ func TestRssGenerationWithClosed(t *testing.T) {
 	rssContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-closed]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#closed-2021-10-08T12:44:47Z</guid>
       <pubDate>Fri, 08 Oct 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-open]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
   </channel>
@@ -105,6 +500,7 @@ func TestRssGenerationWithClosed(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
@@ -113,12 +509,13 @@ func TestRssGenerationWithClosed(t *testing.T) {
 			CreatedAt: "2021-09-08T12:44:47Z",
 			ClosedAt:  "2021-10-08T12:44:47Z",
 			State:     "closed",
+			Number:    2,
 			Title:     "Another Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Another body",
 		},
 	}
-	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true}})
+	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}})
 	if err != nil {
 		t.Fatalf("Unable to save backup file")
 	}
@@ -131,15 +528,17 @@ func TestRssGenerationWithClosedButOnlyOpen(t *testing.T) {
 	rssContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-open]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
   </channel>
@@ -147,6 +546,7 @@ func TestRssGenerationWithClosedButOnlyOpen(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
@@ -155,12 +555,13 @@ func TestRssGenerationWithClosedButOnlyOpen(t *testing.T) {
 			CreatedAt: "2021-09-08T12:44:47Z",
 			ClosedAt:  "2021-10-08T12:44:47Z",
 			State:     "closed",
+			Number:    2,
 			Title:     "Another Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Another body",
 		},
 	}
-	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, false, true, false}})
+	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, false, true, false, false, false, false}})
 	if err != nil {
 		t.Fatalf("Unable to save backup file")
 	}
@@ -168,3 +569,338 @@ func TestRssGenerationWithClosedButOnlyOpen(t *testing.T) {
 		t.Fatalf("Rss feed content does not match up")
 	}
 }
+
+func TestRssGenerationMergedPRs(t *testing.T) {
+	merged := GithubIssue{
+		Number:    1,
+		CreatedAt: "2021-09-08T12:44:47Z",
+		ClosedAt:  "2021-10-08T12:44:47Z",
+		State:     "closed",
+		Title:     "Merged PR",
+	}
+	merged.PullRequest.URL = "https://api.github.com/repos/meain/dotfiles/pulls/1"
+	merged.PullRequest.MergedAt = "2021-10-08T12:44:47Z"
+
+	closed := GithubIssue{
+		Number:    2,
+		CreatedAt: "2021-09-08T12:44:47Z",
+		ClosedAt:  "2021-10-09T12:44:47Z",
+		State:     "closed",
+		Title:     "Abandoned PR",
+	}
+	closed.PullRequest.URL = "https://api.github.com/repos/meain/dotfiles/pulls/2"
+
+	data := []GithubIssue{merged, closed}
+
+	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}})
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	for _, title := range []string{"[pr-merged]: Merged PR", "[pr-closed]: Abandoned PR"} {
+		if !strings.Contains(content, "<title>"+title+"</title>") {
+			t.Fatalf("Feed does not contain %v: %s", title, content)
+		}
+	}
+	if strings.Contains(content, "[pr-closed]: Merged PR") {
+		t.Fatalf("Merged PR should not show up as closed")
+	}
+
+	content, err = generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: getModesFromList([]string{"pm"})})
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "[pr-merged]: Merged PR") || strings.Contains(content, "Abandoned PR") {
+		t.Fatalf("Only merged PRs should be in the feed: %s", content)
+	}
+
+	// older pc feeds keep getting merged prs
+	content, err = generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: getModesFromList([]string{"pc"})})
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "[pr-merged]: Merged PR") || !strings.Contains(content, "[pr-closed]: Abandoned PR") {
+		t.Fatalf("Closed and merged PRs should be in the feed: %s", content)
+	}
+
+	content, err = generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: getModesFromList([]string{"pc", "pm"})})
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "[pr-merged]: Merged PR") || !strings.Contains(content, "[pr-closed]: Abandoned PR") {
+		t.Fatalf("Closed and merged PRs should be in the feed: %s", content)
+	}
+}
+
+func TestIssueTransitions(t *testing.T) {
+	issue := GithubIssue{
+		Number:    1,
+		CreatedAt: "2021-09-01T12:44:47Z",
+		ClosedAt:  "2021-09-05T12:44:47Z",
+		State:     "closed",
+	}
+	events := []GithubIssueEvent{
+		{ID: 13, Event: "closed", CreatedAt: "2021-09-05T12:44:47Z"},
+		{ID: 12, Event: "reopened", CreatedAt: "2021-09-03T12:44:47Z"},
+		{ID: 11, Event: "closed", CreatedAt: "2021-09-02T12:44:47Z"},
+	}
+	for i := range events {
+		events[i].Issue.Number = 1
+	}
+
+	table := []struct {
+		name   string
+		events []GithubIssueEvent
+		ids    []string
+	}{
+		{"no events", nil, []string{"closed-2021-09-05T12:44:47Z"}},
+		{"all events", events, []string{"closed-2021-09-02T12:44:47Z", "reopened-2021-09-03T12:44:47Z", "closed-2021-09-05T12:44:47Z"}},
+		{"missing latest close", events[1:], []string{"closed-2021-09-02T12:44:47Z", "reopened-2021-09-03T12:44:47Z", "closed-2021-09-05T12:44:47Z"}},
+		{"missing first close", events[:2], []string{"reopened-2021-09-03T12:44:47Z", "closed-2021-09-05T12:44:47Z"}},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			issue.Events = tc.events
+			var ids []string
+			for _, transition := range issueTransitions(issue, "issue") {
+				ids = append(ids, transition.Id)
+			}
+			if !cmp.Equal(tc.ids, ids) {
+				t.Fatalf("values are not the same %s", cmp.Diff(tc.ids, ids))
+			}
+		})
+	}
+}
+
+// An issue first closed before the window of cached events and then
+// reopened and closed again should not reuse the id of the first close
+func TestIssueTransitionsCloseOutsideEvents(t *testing.T) {
+	issue := GithubIssue{
+		Number:    1,
+		CreatedAt: "2021-09-01T12:44:47Z",
+		ClosedAt:  "2021-09-02T12:44:47Z",
+		State:     "closed",
+	}
+	seen := map[string]bool{}
+	for _, transition := range issueTransitions(issue, "issue") {
+		seen[transition.Id] = true
+	}
+
+	issue.ClosedAt = "2021-09-05T12:44:47Z"
+	issue.Events = []GithubIssueEvent{
+		{ID: 13, Event: "closed", CreatedAt: "2021-09-05T12:44:47Z"},
+		{ID: 12, Event: "reopened", CreatedAt: "2021-09-03T12:44:47Z"},
+	}
+	transitions := issueTransitions(issue, "issue")
+	if len(transitions) != 2 {
+		t.Fatalf("Expected reopen and close, got %v", transitions)
+	}
+	for _, transition := range transitions {
+		if seen[transition.Id] {
+			t.Fatalf("Id %v reused for a later transition", transition.Id)
+		}
+	}
+}
+
+func TestRssGenerationReopened(t *testing.T) {
+	data := []GithubIssue{
+		GithubIssue{
+			Number:    1,
+			CreatedAt: "2021-09-01T12:44:47Z",
+			ClosedAt:  "2021-09-05T12:44:47Z",
+			State:     "closed",
+			Title:     "Sample Entry",
+		},
+	}
+	attachEvents(data, []GithubIssueEvent{
+		{ID: 13, Event: "closed", CreatedAt: "2021-09-05T12:44:47Z"},
+		{ID: 12, Event: "reopened", CreatedAt: "2021-09-03T12:44:47Z"},
+		{ID: 11, Event: "closed", CreatedAt: "2021-09-02T12:44:47Z"},
+	})
+	if len(data[0].Events) != 0 {
+		t.Fatalf("Events of other issues should not be attached")
+	}
+
+	events := []GithubIssueEvent{
+		{ID: 13, Event: "closed", CreatedAt: "2021-09-05T12:44:47Z"},
+		{ID: 12, Event: "reopened", CreatedAt: "2021-09-03T12:44:47Z"},
+		{ID: 11, Event: "closed", CreatedAt: "2021-09-02T12:44:47Z"},
+	}
+	for i := range events {
+		events[i].Issue.Number = 1
+	}
+	attachEvents(data, events)
+
+	content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: getModesFromList([]string{"ic", "ir"})})
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+
+	rssContent := `      <title>[issue-closed]: Sample Entry</title>
+      <link></link>
+      <description></description>
+      <guid>https://github.com/meain/dotfiles/issues/1#closed-2021-09-05T12:44:47Z</guid>
+      <pubDate>Sun, 05 Sep 2021 12:44:47 +0000</pubDate>
+    </item>
+    <item>
+      <title>[issue-reopened]: Sample Entry</title>
+      <link></link>
+      <description></description>
+      <guid>https://github.com/meain/dotfiles/issues/1#reopened-2021-09-03T12:44:47Z</guid>
+      <pubDate>Fri, 03 Sep 2021 12:44:47 +0000</pubDate>
+    </item>
+    <item>
+      <title>[issue-closed]: Sample Entry</title>
+      <link></link>
+      <description></description>
+      <guid>https://github.com/meain/dotfiles/issues/1#closed-2021-09-02T12:44:47Z</guid>
+      <pubDate>Thu, 02 Sep 2021 12:44:47 +0000</pubDate>
+    </item>
+  </channel>`
+	if !strings.Contains(content, rssContent) {
+		t.Fatalf("Rss feed content does not match up: %s", content)
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	stored := `[{"id":2,"event":"closed"},{"id":1,"event":"closed"}]`
+	updates := `[{"id":4,"event":"labeled"},{"id":3,"event":"reopened"},{"id":2,"event":"closed"}]`
+
+	merged, err := mergeEvents([]byte(stored), []byte(updates))
+	if err != nil {
+		t.Fatalf("Unable to merge events: %s", err)
+	}
+
+	expected := `[{"id":3,"event":"reopened"},{"id":2,"event":"closed"},{"id":1,"event":"closed"}]`
+	if string(merged) != expected {
+		t.Fatalf("Invalid merge, expected %v, got %v", expected, string(merged))
+	}
+}
+
+func TestGetIssueFeedWithEvents(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/events").
+		Reply(200).
+		BodyString(`[{"id":2,"event":"reopened","created_at":"2021-09-03T12:44:47Z","issue":{"number":1}},{"id":1,"event":"closed","created_at":"2021-09-02T12:44:47Z","issue":{"number":1}}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues").
+		Reply(200).
+		BodyString(`[{"number":1,"title":"Sample Entry","state":"open","created_at":"2021-09-01T12:44:47Z"}]`)
+
+	content, err := getIssueFeed(RunConfig{Repo: "meain/dotfiles", Modes: getModesFromList([]string{"ir"})}, 0)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "<title>[issue-reopened]: Sample Entry</title>") {
+		t.Fatalf("Reopen event missing from feed: %s", content)
+	}
+}
+
+func TestItemGuid(t *testing.T) {
+	data := []GithubIssue{
+		GithubIssue{
+			Number:    12,
+			CreatedAt: "2021-09-08T12:44:47Z",
+			ClosedAt:  "2021-10-08T12:44:47Z",
+			State:     "closed",
+			Title:     "Sample Entry",
+			HTMLURL:   "https://github.com/meain/dotfiles/pull/12",
+			Body:      "Some body",
+		},
+	}
+
+	table := []struct {
+		format string
+		guids  []string
+	}{
+		{"rss", []string{
+			"<guid>https://github.com/meain/dotfiles/issues/12#open</guid>",
+			"<guid>https://github.com/meain/dotfiles/issues/12#closed-2021-10-08T12:44:47Z</guid>",
+		}},
+		{"atom", []string{
+			"<id>https://github.com/meain/dotfiles/issues/12#open</id>",
+			"<id>https://github.com/meain/dotfiles/issues/12#closed-2021-10-08T12:44:47Z</id>",
+		}},
+		{"json", []string{
+			`"id": "https://github.com/meain/dotfiles/issues/12#open"`,
+			`"id": "https://github.com/meain/dotfiles/issues/12#closed-2021-10-08T12:44:47Z"`,
+		}},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.format, func(t *testing.T) {
+			content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}, Format: tc.format})
+			if err != nil {
+				t.Fatalf("Unable to generate feed: %s", err)
+			}
+			for _, guid := range tc.guids {
+				if !strings.Contains(content, guid) {
+					t.Fatalf("Feed does not contain %v: %s", guid, content)
+				}
+			}
+		})
+	}
+}
+
+func TestGetIssueFeedMulti(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues$").
+		Reply(200).
+		BodyString(`[{"number":1,"title":"Older Entry","state":"open","created_at":"2021-09-01T12:44:47Z"}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/gh-issues-to-rss/issues$").
+		Reply(200).
+		BodyString(`[{"number":1,"title":"Newer Entry","state":"open","created_at":"2021-09-05T12:44:47Z"}]`)
+
+	rc := RunConfig{
+		Repos: []string{"meain/dotfiles", "meain/gh-issues-to-rss", "meain/missing"},
+		Modes: Modes{true, true, true, true, true, true, true},
+	}
+	content, err := getIssueFeed(rc, 0)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+
+	newer := strings.Index(content, "<title>[meain/gh-issues-to-rss] [issue-open]: Newer Entry</title>")
+	older := strings.Index(content, "<title>[meain/dotfiles] [issue-open]: Older Entry</title>")
+	if newer == -1 || older == -1 {
+		t.Fatalf("Items missing from combined feed: %s", content)
+	}
+	if newer > older {
+		t.Fatalf("Items in combined feed not sorted by time: %s", content)
+	}
+	if !strings.Contains(content, "<guid>https://github.com/meain/dotfiles/issues/1#open</guid>") ||
+		!strings.Contains(content, "<guid>https://github.com/meain/gh-issues-to-rss/issues/1#open</guid>") {
+		t.Fatalf("Items in combined feed do not use their own repo for guid: %s", content)
+	}
+	if !strings.Contains(content, "<title>meain/dotfiles, meain/gh-issues-to-rss, meain/missing</title>") {
+		t.Fatalf("Invalid title for combined feed: %s", content)
+	}
+
+	// the server wide title template is used if it has been changed
+	titleTemplateBackup := titleTemplate
+	defer func() { titleTemplate = titleTemplateBackup }()
+	titleTemplate = "numbered"
+	content, err = getIssueFeed(rc, time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "<title>#1 Older Entry (meain/dotfiles)</title>") {
+		t.Fatalf("Server title template not used for combined feed: %s", content)
+	}
+
+	rc.Repos = []string{"meain/missing"}
+	if _, err := getIssueFeed(rc, 0); err == nil {
+		t.Fatalf("Expected error when all repos fail")
+	}
+}
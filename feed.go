@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// Named title templates which can be picked per request
+var titlePresets = map[string]string{
+	"default":  "[{{.Type}}-{{.Event}}]: {{.Title}}",
+	"numbered": "{{if .Number}}#{{.Number}} {{end}}{{.Title}} ({{.Repo}})", // releases have no number
+	"repo":     "[{{.Repo}}] [{{.Type}}-{{.Event}}]: {{.Title}}",
+}
+
+// Values available from within title templates
+type titleData struct {
+	Repo   string
+	Number int64
+	Type   string // issue, pr or release
+	Event  string // open, closed, merged, reopened, comment; published, prerelease or draft
+	Title  string
+	Labels []string
+	Author string
+}
+
+var titleFuncs = template.FuncMap{"join": strings.Join}
+
+// parseTitleTemplate parses `tmpl` which can either be the name of
+// one of titlePresets or a template. The template is run once against
+// empty values so that references to unknown fields error out early
+// rather than when generating the feed.
+func parseTitleTemplate(tmpl string) (*template.Template, error) {
+	if preset, ok := titlePresets[tmpl]; ok {
+		tmpl = preset
+	}
+
+	t, err := template.New("title").Funcs(titleFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Execute(io.Discard, titleData{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func renderTitle(t *template.Template, data titleData) (string, error) {
+	var buf strings.Builder
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// feeds.Item has no place for categories, so we keep them on the side
+// and add them in when rendering the feed
+type itemCategories map[*feeds.Item][]string
+
+type rssItem struct {
+	*feeds.RssItem
+	Categories []string `xml:"category"`
+}
+
+type rssChannel struct {
+	*feeds.RssFeed
+	Items []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName          xml.Name `xml:"rss"`
+	Version          string   `xml:"version,attr"`
+	ContentNamespace string   `xml:"xmlns:content,attr"`
+	Channel          *rssChannel
+}
+
+func (f *rssFeed) FeedXml() interface{} {
+	return f
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	*feeds.AtomEntry
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	*feeds.AtomFeed
+	Entries []*atomEntry `xml:"entry"`
+}
+
+func (f *atomFeed) FeedXml() interface{} {
+	return f
+}
+
+// renderFeed converts the feed into the requested format (rss if empty)
+func renderFeed(feed *feeds.Feed, categories itemCategories, format string) (string, error) {
+	switch format {
+	case "atom":
+		af := (&feeds.Atom{Feed: feed}).AtomFeed()
+		wrapped := &atomFeed{AtomFeed: af}
+		for i, entry := range af.Entries {
+			e := &atomEntry{AtomEntry: entry}
+			for _, category := range categories[feed.Items[i]] {
+				e.Categories = append(e.Categories, atomCategory{category})
+			}
+			wrapped.Entries = append(wrapped.Entries, e)
+		}
+		return feeds.ToXML(wrapped)
+	case "json":
+		jf := (&feeds.JSON{Feed: feed}).JSONFeed()
+		for i, item := range jf.Items {
+			item.Tags = categories[feed.Items[i]]
+		}
+		return jf.ToJSON()
+	default:
+		rf := (&feeds.Rss{Feed: feed}).RssFeed()
+		channel := &rssChannel{RssFeed: rf}
+		for i, item := range rf.Items {
+			channel.Items = append(channel.Items, &rssItem{item, categories[feed.Items[i]]})
+		}
+		return feeds.ToXML(&rssFeed{
+			Version:          "2.0",
+			ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+			Channel:          channel,
+		})
+	}
+}
+
+// issueFooter lists out metadata about the issue like milestone and
+// assignees to be shown after the body. Empty values are skipped.
+func issueFooter(entry GithubIssue, bodyFormat string) string {
+	var lines [][2]string
+
+	var labels []string
+	for _, label := range entry.Labels {
+		labels = append(labels, label.Name)
+	}
+	if len(labels) > 0 {
+		lines = append(lines, [2]string{"Labels", strings.Join(labels, ", ")})
+	}
+
+	if entry.Milestone != nil {
+		lines = append(lines, [2]string{"Milestone", entry.Milestone.Title})
+	}
+
+	var assignees []string
+	for _, assignee := range entry.Assignees {
+		assignees = append(assignees, assignee.Login)
+	}
+	if len(assignees) > 0 {
+		lines = append(lines, [2]string{"Assignees", strings.Join(assignees, ", ")})
+	}
+
+	if entry.Comments > 0 {
+		lines = append(lines, [2]string{"Comments", strconv.FormatInt(entry.Comments, 10)})
+	}
+
+	if entry.AuthorAssociation != "" && entry.AuthorAssociation != "NONE" {
+		association := strings.ToLower(strings.ReplaceAll(entry.AuthorAssociation, "_", " "))
+		lines = append(lines, [2]string{"Author association", association})
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	if bodyFormat == "text" || bodyFormat == "raw" {
+		footer := "\n\n---\n"
+		for _, line := range lines {
+			footer += line[0] + ": " + html.EscapeString(line[1]) + "\n"
+		}
+		return strings.TrimSuffix(footer, "\n")
+	}
+
+	footer := "<hr><ul>"
+	for _, line := range lines {
+		footer += "<li><strong>" + line[0] + ":</strong> " + html.EscapeString(line[1]) + "</li>"
+	}
+	return footer + "</ul>"
+}
+
+// sortItems sorts items newest first
+func sortItems(items []*feeds.Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Created.After(items[j].Created)
+	})
+}
+
+var relativeTime = regexp.MustCompile(`^(\d+)([hdw])$`)
+
+// parseTimeBound parses the since/until options. These can either be
+// absolute (2021-09-08 or RFC3339) or relative to `now` like 12h, 7d
+// or 2w.
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if m := relativeTime.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[2]]
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("invalid time " + value + ", use a date like 2021-09-08 or a relative time like 12h, 7d or 2w")
+}
+
+// limitItems drops items with event times outside of since and until
+// (if given) and if `limit` is set, keeps only the `limit` most recent
+// ones, newest first.
+func limitItems(items []*feeds.Item, rc RunConfig, now time.Time) ([]*feeds.Item, error) {
+	var since, until time.Time
+	var err error
+	if rc.Since != "" {
+		if since, err = parseTimeBound(rc.Since, now); err != nil {
+			return nil, err
+		}
+	}
+	if rc.Until != "" {
+		if until, err = parseTimeBound(rc.Until, now); err != nil {
+			return nil, err
+		}
+	}
+
+	var limited []*feeds.Item
+	for _, item := range items {
+		if !since.IsZero() && item.Created.Before(since) {
+			continue
+		}
+		if !until.IsZero() && item.Created.After(until) {
+			continue
+		}
+		limited = append(limited, item)
+	}
+
+	if rc.Limit > 0 {
+		sortItems(limited)
+		if len(limited) > rc.Limit {
+			limited = limited[:rc.Limit]
+		}
+	}
+
+	return limited, nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestIssueFooter(t *testing.T) {
+	issue := GithubIssue{
+		Labels:            []GithubIssueLabel{{Name: "bug"}, {Name: "p0"}},
+		Milestone:         &GithubMilestone{Title: "v1.0"},
+		Assignees:         []GithubUser{{Login: "meain"}, {Login: "ain"}},
+		Comments:          3,
+		AuthorAssociation: "FIRST_TIME_CONTRIBUTOR",
+	}
+
+	footer := issueFooter(issue, "")
+	expected := "<hr><ul>" +
+		"<li><strong>Labels:</strong> bug, p0</li>" +
+		"<li><strong>Milestone:</strong> v1.0</li>" +
+		"<li><strong>Assignees:</strong> meain, ain</li>" +
+		"<li><strong>Comments:</strong> 3</li>" +
+		"<li><strong>Author association:</strong> first time contributor</li>" +
+		"</ul>"
+	if footer != expected {
+		t.Fatalf("Invalid footer, expected %v, got %v", expected, footer)
+	}
+
+	footer = issueFooter(issue, "text")
+	if !strings.HasPrefix(footer, "\n\n---\nLabels: bug, p0\nMilestone: v1.0\n") {
+		t.Fatalf("Invalid text footer, got %v", footer)
+	}
+
+	footer = issueFooter(GithubIssue{Labels: []GithubIssueLabel{{Name: "<script>"}}}, "text")
+	if strings.Contains(footer, "<script>") {
+		t.Fatalf("Text footer not escaped, got %v", footer)
+	}
+
+	if footer := issueFooter(GithubIssue{AuthorAssociation: "NONE"}, ""); footer != "" {
+		t.Fatalf("Expected empty footer, got %v", footer)
+	}
+}
+
+func TestFeedCategories(t *testing.T) {
+	data := []GithubIssue{
+		GithubIssue{
+			Number:    1,
+			CreatedAt: "2021-09-08T12:44:47Z",
+			Title:     "Sample Entry",
+			Labels:    []GithubIssueLabel{{Name: "bug"}, {Name: "help wanted"}},
+		},
+	}
+
+	table := []struct {
+		format     string
+		categories string
+	}{
+		{"rss", "<category>bug</category>\n      <category>help wanted</category>"},
+		{"atom", `<category term="bug"></category>` + "\n    " + `<category term="help wanted"></category>`},
+		{"json", `"tags": [` + "\n        \"bug\",\n        \"help wanted\"\n      ]"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.format, func(t *testing.T) {
+			content, err := generateRss(data, RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}, Format: tc.format})
+			if err != nil {
+				t.Fatalf("Unable to generate feed: %s", err)
+			}
+			if !strings.Contains(content, tc.categories) {
+				t.Fatalf("Categories missing from feed: %s", content)
+			}
+		})
+	}
+}
+
+func TestTitleTemplates(t *testing.T) {
+	data := []GithubIssue{
+		GithubIssue{
+			Number:    12,
+			CreatedAt: "2021-09-08T12:44:47Z",
+			Title:     "Sample Entry",
+			Labels:    []GithubIssueLabel{{Name: "bug"}, {Name: "p0"}},
+			User:      GithubUser{Login: "meain"},
+		},
+	}
+
+	table := []struct {
+		name     string
+		template string
+		title    string
+	}{
+		{"default", "", "<title>[issue-open]: Sample Entry</title>"},
+		{"preset", "numbered", "<title>#12 Sample Entry (meain/dotfiles)</title>"},
+		{"repo preset", "repo", "<title>[meain/dotfiles] [issue-open]: Sample Entry</title>"},
+		{"custom", "{{.Author}}: {{.Title}} [{{join .Labels \",\"}}]", "<title>meain: Sample Entry [bug,p0]</title>"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := RunConfig{Repo: "meain/dotfiles", Modes: Modes{true, true, true, true, true, true, true}, TitleTemplate: tc.template}
+			content, err := generateRss(data, rc)
+			if err != nil {
+				t.Fatalf("Unable to generate feed: %s", err)
+			}
+			if !strings.Contains(content, tc.title) {
+				t.Fatalf("Expected %v in feed: %s", tc.title, content)
+			}
+		})
+	}
+}
+
+func TestParseTitleTemplateInvalid(t *testing.T) {
+	for _, tmpl := range []string{"{{.Title", "{{.Unknown}}"} {
+		if _, err := parseTitleTemplate(tmpl); err == nil {
+			t.Fatalf("Expected error for template %v", tmpl)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2021, 10, 8, 12, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"12h", time.Date(2021, 10, 8, 0, 0, 0, 0, time.UTC)},
+		{"7d", time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)},
+		{"2w", time.Date(2021, 9, 24, 12, 0, 0, 0, time.UTC)},
+		{"2021-09-08", time.Date(2021, 9, 8, 0, 0, 0, 0, time.UTC)},
+		{"2021-09-08T12:44:47Z", time.Date(2021, 9, 8, 12, 44, 47, 0, time.UTC)},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseTimeBound(tc.value, now)
+			if err != nil {
+				t.Fatalf("Unable to parse time: %s", err)
+			}
+			if !got.Equal(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	for _, value := range []string{"yesterday", "7", "7m", "2021-13-01"} {
+		if _, err := parseTimeBound(value, now); err == nil {
+			t.Fatalf("Expected error for %v", value)
+		}
+	}
+}
+
+func TestLimitItems(t *testing.T) {
+	now := time.Date(2021, 10, 8, 12, 0, 0, 0, time.UTC)
+	items := []*feeds.Item{
+		{Title: "a", Created: now.Add(-10 * 24 * time.Hour)},
+		{Title: "b", Created: now.Add(-1 * time.Hour)},
+		{Title: "c", Created: now.Add(-3 * 24 * time.Hour)},
+		{Title: "d", Created: now.Add(-2 * time.Hour)},
+	}
+
+	table := []struct {
+		name   string
+		rc     RunConfig
+		titles string
+	}{
+		{"no limits", RunConfig{}, "abcd"},
+		{"since", RunConfig{Since: "7d"}, "bcd"},
+		{"until", RunConfig{Until: "1d"}, "ac"},
+		{"window", RunConfig{Since: "7d", Until: "1d"}, "c"},
+		{"limit", RunConfig{Limit: 2}, "bd"},
+		{"limit over count", RunConfig{Limit: 10}, "bdca"},
+		{"since and limit", RunConfig{Since: "2021-10-01", Limit: 1}, "b"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			limited, err := limitItems(items, tc.rc, now)
+			if err != nil {
+				t.Fatalf("Unable to limit items: %s", err)
+			}
+			titles := ""
+			for _, item := range limited {
+				titles += item.Title
+			}
+			if titles != tc.titles {
+				t.Fatalf("Expected %v, got %v", tc.titles, titles)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// A filter expression like `(label:bug or label:regression) and not
+// label:wontfix` is parsed into a tree of filterNode which is then
+// evaluated against each issue. Terms next to each other without an
+// operator are ANDed together.
+//
+//	expr  := and ("or" and)*
+//	and   := unary ["and"] unary ...
+//	unary := "not" unary | "(" expr ")" | term
+//	term  := field ":" value | "title~" regex
+type filterNode interface {
+	match(issue GithubIssue) bool
+}
+
+type andNode []filterNode
+
+func (n andNode) match(issue GithubIssue) bool {
+	for _, child := range n {
+		if !child.match(issue) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []filterNode
+
+func (n orNode) match(issue GithubIssue) bool {
+	for _, child := range n {
+		if child.match(issue) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct {
+	child filterNode
+}
+
+func (n notNode) match(issue GithubIssue) bool {
+	return !n.child.match(issue)
+}
+
+// termNode matches a single field of the issue against a value
+type termNode struct {
+	field string
+	value string
+}
+
+// Fields which can be used in filter terms
+var filterFields = []string{"label", "author", "assignee", "milestone", "association", "state", "type"}
+
+// Values Github uses for author_association
+var authorAssociations = []string{
+	"COLLABORATOR", "CONTRIBUTOR", "FIRST_TIMER", "FIRST_TIME_CONTRIBUTOR",
+	"MANNEQUIN", "MEMBER", "NONE", "OWNER",
+}
+
+func (n termNode) match(issue GithubIssue) bool {
+	switch n.field {
+	case "author":
+		return issue.User.Login == n.value
+	case "assignee":
+		if n.value == "unassigned" {
+			return len(issue.Assignees) == 0 && issue.Assignee == nil
+		}
+		for _, assignee := range issue.Assignees {
+			if assignee.Login == n.value {
+				return true
+			}
+		}
+		return issue.Assignee != nil && issue.Assignee.Login == n.value
+	case "milestone":
+		if n.value == "none" {
+			return issue.Milestone == nil
+		}
+		return issue.Milestone != nil && issue.Milestone.Title == n.value
+	case "association":
+		return strings.EqualFold(issue.AuthorAssociation, n.value)
+	case "state":
+		if n.value == "merged" {
+			return issue.PullRequest.MergedAt != ""
+		}
+		return issue.State == n.value
+	case "type":
+		if n.value == "pr" {
+			return issue.PullRequest.URL != ""
+		}
+		return issue.PullRequest.URL == ""
+	}
+	return false
+}
+
+// labelNode matches if any of the labels on the issue matches any of
+// the patterns. Patterns are compared case-insensitively and can be
+// globs like `area/*` or regexes wrapped in slashes like `/^area-/`.
+type labelNode []*regexp.Regexp
+
+func (n labelNode) match(issue GithubIssue) bool {
+	for _, label := range issue.Labels {
+		for _, re := range n {
+			if re.MatchString(label.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseLabelPatterns parses a label filter value. Multiple patterns
+// can be given separated by `|`, like `bug|regression`, in which case
+// any of them matching is enough. A regex can not be part of a group,
+// use alternation within the regex instead.
+func parseLabelPatterns(value string) (labelNode, error) {
+	if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile("(?i)" + value[1:len(value)-1])
+		if err != nil {
+			return nil, errors.New("invalid label regex " + value + ": " + err.Error())
+		}
+		return labelNode{re}, nil
+	}
+
+	var node labelNode
+	for _, glob := range strings.Split(value, "|") {
+		node = append(node, globRegexp(glob))
+	}
+	return node, nil
+}
+
+// globRegexp converts a glob into a case-insensitive regex. `*` matches
+// any number of characters and `?` a single one.
+func globRegexp(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile("(?i)^" + pattern + "$")
+}
+
+// botNode matches issues created by bots. Github marks bot accounts
+// with type Bot and apps acting on behalf of users are reported via
+// performed_via_github_app, but some bots are regular user accounts
+// and so we also match the login against botLogins.
+type botNode []*regexp.Regexp
+
+func (n botNode) match(issue GithubIssue) bool {
+	if issue.User.Type == "Bot" || issue.PerformedViaGithubApp != nil {
+		return true
+	}
+	for _, re := range n {
+		if re.MatchString(issue.User.Login) {
+			return true
+		}
+	}
+	return false
+}
+
+func newBotNode() botNode {
+	var node botNode
+	for _, login := range botLogins {
+		node = append(node, globRegexp(login))
+	}
+	return node
+}
+
+// textNode matches issues which mention the pattern in their title or
+// body, ignoring case. Like labels, regexes are wrapped in slashes.
+type textNode struct {
+	re *regexp.Regexp
+}
+
+func (n textNode) match(issue GithubIssue) bool {
+	return n.re.MatchString(issue.Title) || n.re.MatchString(issue.Body)
+}
+
+func parseTextPattern(value string) (textNode, error) {
+	if len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		re, err := regexp.Compile("(?i)" + value[1:len(value)-1])
+		if err != nil {
+			return textNode{}, errors.New("invalid text regex " + value + ": " + err.Error())
+		}
+		return textNode{re}, nil
+	}
+	return textNode{regexp.MustCompile("(?i)" + regexp.QuoteMeta(value))}, nil
+}
+
+type titleNode struct {
+	re *regexp.Regexp
+}
+
+func (n titleNode) match(issue GithubIssue) bool {
+	return n.re.MatchString(issue.Title)
+}
+
+type filterTokenKind int
+
+const (
+	tokenWord filterTokenKind = iota
+	tokenOpen
+	tokenClose
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+// tokenizeFilter splits the expression into parens and words. Double
+// quotes can be used within words for values with spaces or parens,
+// like label:"help wanted" or title~"(foo|bar)".
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{tokenOpen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{tokenClose, ")"})
+			i++
+		default:
+			var word strings.Builder
+			for i < len(runes) && !strings.ContainsRune(" \t\n()", runes[i]) {
+				if runes[i] != '"' {
+					word.WriteRune(runes[i])
+					i++
+					continue
+				}
+
+				i++ // opening quote
+				for i < len(runes) && runes[i] != '"' {
+					if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+						i++
+					}
+					word.WriteRune(runes[i])
+					i++
+				}
+				if i == len(runes) {
+					return nil, errors.New("unterminated quote in filter")
+				}
+				i++ // closing quote
+			}
+			tokens = append(tokens, filterToken{tokenWord, word.String()})
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok != nil && tok.kind == tokenWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := orNode{node}
+	for p.isKeyword("or") {
+		p.pos++
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := andNode{node}
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind == tokenClose || p.isKeyword("or") {
+			break
+		}
+		if p.isKeyword("and") {
+			p.pos++
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, errors.New("unexpected end of filter")
+	}
+
+	if p.isKeyword("not") {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+
+	p.pos++
+	switch tok.kind {
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.peek(); tok == nil || tok.kind != tokenClose {
+			return nil, errors.New("missing closing paren in filter")
+		}
+		p.pos++
+		return node, nil
+	case tokenClose:
+		return nil, errors.New("unexpected closing paren in filter")
+	}
+
+	return parseFilterTerm(tok.text)
+}
+
+func parseFilterTerm(term string) (filterNode, error) {
+	if strings.HasPrefix(term, "title~") {
+		re, err := regexp.Compile(strings.TrimPrefix(term, "title~"))
+		if err != nil {
+			return nil, errors.New("invalid title regex in filter: " + err.Error())
+		}
+		return titleNode{re}, nil
+	}
+
+	splits := strings.SplitN(term, ":", 2)
+	if len(splits) != 2 || splits[1] == "" {
+		return nil, errors.New("invalid term " + term + " in filter, use field:value")
+	}
+
+	field, value := splits[0], splits[1]
+	if !isIn(field, filterFields) {
+		return nil, errors.New("unknown field " + field + " in filter, use one of [" + strings.Join(filterFields, ",") + "] or title~")
+	}
+	if field == "association" {
+		return parseAssociation(value)
+	}
+	if field == "state" && !isIn(value, []string{"open", "closed", "merged"}) {
+		return nil, errors.New("invalid state " + value + " in filter, use one of [open,closed,merged]")
+	}
+	if field == "type" && !isIn(value, []string{"issue", "pr"}) {
+		return nil, errors.New("invalid type " + value + " in filter, use one of [issue,pr]")
+	}
+
+	if field == "label" {
+		return parseLabelPatterns(value)
+	}
+
+	return termNode{field, value}, nil
+}
+
+func parseAssociation(value string) (filterNode, error) {
+	if !isIn(strings.ToUpper(value), authorAssociations) {
+		return nil, errors.New("invalid author association " + value + ", use one of [" + strings.Join(authorAssociations, ",") + "]")
+	}
+	return termNode{"association", value}, nil
+}
+
+// parseFilter parses a filter expression, see filterNode for the syntax
+func parseFilter(expr string) (filterNode, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return andNode{}, nil
+	}
+
+	p := &filterParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(tokens) {
+		return nil, errors.New("unexpected " + tokens[p.pos].text + " in filter")
+	}
+	return node, nil
+}
+
+// compileFilter combines the individual filters along with the filter
+// expression in the config into a single filter. All labels (or one
+// from each `|` group) and texts have to match, while for users,
+// assignees, milestones and associations matching any one is enough.
+// Nothing from the excluded lists can match, and neither can bots if
+// they are excluded.
+func compileFilter(rc RunConfig) (filterNode, error) {
+	filter := andNode{}
+
+	for _, label := range rc.Labels {
+		node, err := parseLabelPatterns(label)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, node)
+	}
+	for _, label := range rc.NotLabels {
+		node, err := parseLabelPatterns(label)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, notNode{node})
+	}
+	for _, text := range rc.Text {
+		node, err := parseTextPattern(text)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, node)
+	}
+	for _, text := range rc.NotText {
+		node, err := parseTextPattern(text)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, notNode{node})
+	}
+	anyOf := func(field string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		node := orNode{}
+		for _, value := range values {
+			node = append(node, termNode{field, value})
+		}
+		filter = append(filter, node)
+	}
+	noneOf := func(field string, values []string) {
+		for _, value := range values {
+			filter = append(filter, notNode{termNode{field, value}})
+		}
+	}
+
+	for _, association := range append(rc.Associations, rc.NotAssociations...) {
+		if _, err := parseAssociation(association); err != nil {
+			return nil, err
+		}
+	}
+
+	anyOf("author", rc.Users)
+	noneOf("author", rc.NotUsers)
+	anyOf("assignee", rc.Assignees)
+	noneOf("assignee", rc.NotAssignees)
+	anyOf("milestone", rc.Milestones)
+	noneOf("milestone", rc.NotMilestones)
+	anyOf("association", rc.Associations)
+	noneOf("association", rc.NotAssociations)
+
+	if rc.NoBots {
+		filter = append(filter, notNode{newBotNode()})
+	}
+
+	if rc.Query != "" {
+		query, err := parseFilter(rc.Query)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, query)
+	}
+
+	return filter, nil
+}
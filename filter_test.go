@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	bug := GithubIssue{
+		Number: 1,
+		Title:  "Crash on startup",
+		State:  "open",
+		Labels: []GithubIssueLabel{{Name: "bug"}, {Name: "help wanted"}},
+		User:   GithubUser{Login: "meain"},
+	}
+	regression := GithubIssue{
+		Number:    2,
+		Title:     "Slow rendering",
+		State:     "closed",
+		Labels:    []GithubIssueLabel{{Name: "regression"}, {Name: "wontfix"}},
+		User:      GithubUser{Login: "ain"},
+		Milestone: &GithubMilestone{Title: "v1.0"},
+
+		AuthorAssociation: "FIRST_TIME_CONTRIBUTOR",
+	}
+	pr := GithubIssue{
+		Number:    3,
+		Title:     "Fix crash on startup",
+		State:     "closed",
+		Labels:    []GithubIssueLabel{{Name: "bug"}},
+		User:      GithubUser{Login: "dependabot[bot]"},
+		Assignees: []GithubUser{{Login: "meain"}},
+	}
+	pr.PullRequest.URL = "https://api.github.com/repos/meain/dotfiles/pulls/3"
+	pr.PullRequest.MergedAt = "2021-10-08T12:44:47Z"
+	issues := []GithubIssue{bug, regression, pr}
+
+	table := []struct {
+		expr    string
+		numbers []int64
+	}{
+		{"", []int64{1, 2, 3}},
+		{"label:bug", []int64{1, 3}},
+		{"label:bug or label:regression", []int64{1, 2, 3}},
+		{"(label:bug or label:regression) and not label:wontfix and not author:dependabot[bot]", []int64{1}},
+		{"label:bug type:pr", []int64{3}},
+		{"type:issue", []int64{1, 2}},
+		{"state:closed", []int64{2, 3}},
+		{"state:merged", []int64{3}},
+		{"assignee:meain", []int64{3}},
+		{"milestone:v1.0", []int64{2}},
+		{"milestone:none", []int64{1, 3}},
+		{"assignee:unassigned", []int64{1, 2}},
+		{"association:first_time_contributor", []int64{2}},
+		{`label:"help wanted"`, []int64{1}},
+		{"title~^Fix", []int64{3}},
+		{`title~"(?i)crash"`, []int64{1, 3}},
+		{"NOT (label:bug OR state:closed)", nil},
+		{"label:bug and not (type:pr or author:meain)", nil},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := parseFilter(tc.expr)
+			if err != nil {
+				t.Fatalf("Unable to parse filter: %s", err)
+			}
+
+			var numbers []int64
+			for _, issue := range issues {
+				if filter.match(issue) {
+					numbers = append(numbers, issue.Number)
+				}
+			}
+			if len(numbers) != len(tc.numbers) {
+				t.Fatalf("Expected %v, got %v", tc.numbers, numbers)
+			}
+			for i := range numbers {
+				if numbers[i] != tc.numbers[i] {
+					t.Fatalf("Expected %v, got %v", tc.numbers, numbers)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	table := []string{
+		"label:bug or",
+		"(label:bug",
+		"label:bug)",
+		"bug",
+		"label:",
+		"color:red",
+		"state:pending",
+		"type:discussion",
+		"association:stranger",
+		"title~(",
+		`label:"bug`,
+		"not",
+	}
+
+	for _, expr := range table {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseFilter(expr); err == nil {
+				t.Fatalf("Expected error for filter %v", expr)
+			}
+		})
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	issue := GithubIssue{
+		Title:     "Crash on arm64",
+		Body:      "Happens on linux only",
+		Labels:    []GithubIssueLabel{{Name: "bug"}, {Name: "p0"}},
+		User:      GithubUser{Login: "meain"},
+		Assignee:  &GithubUser{Login: "ain"},
+		Assignees: []GithubUser{{Login: "ain"}},
+
+		AuthorAssociation: "MEMBER",
+	}
+
+	table := []struct {
+		name  string
+		rc    RunConfig
+		match bool
+	}{
+		{"empty", RunConfig{}, true},
+		{"labels", RunConfig{Labels: []string{"bug", "p0"}}, true},
+		{"missing label", RunConfig{Labels: []string{"bug", "p1"}}, false},
+		{"not labels", RunConfig{NotLabels: []string{"wontfix", "p0"}}, false},
+		{"users", RunConfig{Users: []string{"ain", "meain"}}, true},
+		{"not users", RunConfig{NotUsers: []string{"meain"}}, false},
+		{"label groups", RunConfig{Labels: []string{"regression|BUG", "p*"}}, true},
+		{"not label glob", RunConfig{NotLabels: []string{"p?"}}, false},
+		{"text", RunConfig{Text: []string{"ARM64", "/linux|darwin/"}}, true},
+		{"missing text", RunConfig{Text: []string{"arm64", "windows"}}, false},
+		{"not text", RunConfig{NotText: []string{"windows", "/\\bcrash/"}}, false},
+		{"assignees", RunConfig{Assignees: []string{"meain", "ain"}}, true},
+		{"unassigned", RunConfig{Assignees: []string{"unassigned"}}, false},
+		{"not unassigned", RunConfig{NotAssignees: []string{"unassigned"}}, true},
+		{"milestones", RunConfig{Milestones: []string{"v1.0"}}, false},
+		{"no milestone", RunConfig{Milestones: []string{"v1.0", "none"}}, true},
+		{"not milestone", RunConfig{NotMilestones: []string{"none"}}, false},
+		{"associations", RunConfig{Associations: []string{"first_time_contributor", "member"}}, true},
+		{"not associations", RunConfig{NotAssociations: []string{"MEMBER", "OWNER"}}, false},
+		{"with query", RunConfig{Labels: []string{"bug"}, Query: "author:meain"}, true},
+		{"with failing query", RunConfig{Labels: []string{"bug"}, Query: "not label:p0"}, false},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := compileFilter(tc.rc)
+			if err != nil {
+				t.Fatalf("Unable to compile filter: %s", err)
+			}
+			if got := filter.match(issue); got != tc.match {
+				t.Fatalf("Expected match to be %v, got %v", tc.match, got)
+			}
+		})
+	}
+}
+
+func TestLabelPatterns(t *testing.T) {
+	issue := GithubIssue{Labels: []GithubIssueLabel{{Name: "Bug"}, {Name: "area/ui"}, {Name: "p1"}}}
+
+	table := []struct {
+		pattern string
+		match   bool
+	}{
+		{"bug", true},
+		{"BUG", true},
+		{"bu", false},
+		{"regression|bug", true},
+		{"regression|wontfix", false},
+		{"area/*", true},
+		{"area/?", false},
+		{"area/??", true},
+		{"*/ui", true},
+		{"area/core|area/ui", true},
+		{"/^p[0-2]$/", true},
+		{"/^P[2-3]$/", false},
+		{"/^(area|kind)//", true},
+		{"a.ea/ui", false},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.pattern, func(t *testing.T) {
+			node, err := parseLabelPatterns(tc.pattern)
+			if err != nil {
+				t.Fatalf("Unable to parse label pattern: %s", err)
+			}
+			if got := node.match(issue); got != tc.match {
+				t.Fatalf("Expected match to be %v, got %v", tc.match, got)
+			}
+		})
+	}
+
+	if _, err := parseLabelPatterns("/(/"); err == nil {
+		t.Fatalf("Expected error for invalid regex")
+	}
+}
+
+func TestCompileFilterInvalidAssociation(t *testing.T) {
+	if _, err := compileFilter(RunConfig{Associations: []string{"stranger"}}); err == nil {
+		t.Fatalf("Expected error for invalid author association")
+	}
+}
+
+func TestTextPatternInvalid(t *testing.T) {
+	if _, err := parseTextPattern("/(/"); err == nil {
+		t.Fatalf("Expected error for invalid regex")
+	}
+	if _, err := parseTextPattern("(literal"); err != nil {
+		t.Fatalf("Unexpected error for substring: %s", err)
+	}
+}
+
+func TestBotFilter(t *testing.T) {
+	human := GithubIssue{Number: 1, User: GithubUser{Login: "meain", Type: "User"}}
+	bot := GithubIssue{Number: 2, User: GithubUser{Login: "some-ci", Type: "Bot"}}
+	app := GithubIssue{Number: 3, User: GithubUser{Login: "meain", Type: "User"}, PerformedViaGithubApp: &GithubApp{Slug: "some-app"}}
+	renovate := GithubIssue{Number: 4, User: GithubUser{Login: "renovate-bot", Type: "User"}}
+	actions := GithubIssue{Number: 5, User: GithubUser{Login: "github-actions[bot]", Type: "User"}}
+	custom := GithubIssue{Number: 6, User: GithubUser{Login: "meain-automation", Type: "User"}}
+
+	filter, err := compileFilter(RunConfig{NoBots: true})
+	if err != nil {
+		t.Fatalf("Unable to compile filter: %s", err)
+	}
+	for _, issue := range []GithubIssue{bot, app, renovate, actions} {
+		if filter.match(issue) {
+			t.Fatalf("Expected issue %v to be filtered out as bot", issue.Number)
+		}
+	}
+	for _, issue := range []GithubIssue{human, custom} {
+		if !filter.match(issue) {
+			t.Fatalf("Expected issue %v to not be filtered out", issue.Number)
+		}
+	}
+
+	botLoginsBackup := botLogins
+	defer func() { botLogins = botLoginsBackup }()
+	botLogins = []string{"*-automation"}
+
+	filter, _ = compileFilter(RunConfig{NoBots: true})
+	if filter.match(custom) || !filter.match(renovate) {
+		t.Fatalf("Bot logins not used when filtering bots")
+	}
+}
+
+func TestDecodePerformedViaGithubApp(t *testing.T) {
+	var issues []GithubIssue
+	content := `[{"number":1,"performed_via_github_app":{"id":1,"slug":"dependabot","name":"Dependabot"}},{"number":2,"performed_via_github_app":null}]`
+	if err := json.Unmarshal([]byte(content), &issues); err != nil {
+		t.Fatalf("Unable to decode issues: %s", err)
+	}
+	if issues[0].PerformedViaGithubApp == nil || issues[0].PerformedViaGithubApp.Slug != "dependabot" {
+		t.Fatalf("Github app not decoded: %v", issues[0].PerformedViaGithubApp)
+	}
+	if issues[1].PerformedViaGithubApp != nil {
+		t.Fatalf("Expected no Github app, got %v", issues[1].PerformedViaGithubApp)
+	}
+}
@@ -18,7 +18,7 @@
             pname = "gh-issues-to-rss";
             version = "dev";
             src = ./.;
-            vendorHash = "sha256-30tnt3fYXDDm+YQgY65dUcytDokrUdCChOU5LMgnGYE=";
+            vendorHash = "sha256-/LR4DHLQNBxB+tdu7E3YJzfw+deidYyxUO/2SMiFCpc=";
             doCheck = false;
           };
 
@@ -8,21 +8,40 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
 var baseUrl = "https://api.github.com/repos/"
+var apiUrl = "https://api.github.com/"
 var cacheLocation = "/tmp/gh-issues-to-rss-cache"
+var cache cacheBackend = fileCache{}
+
+// Serve expired cache content and refresh it in the background
+var staleWhileRevalidate = false
+
+// Title template (preset name or template) used when a request does
+// not pick one
+var titleTemplate = "default"
+
+// Login patterns of accounts to treat as bots in addition to the ones
+// Github marks as such
+var botLogins = []string{"*[bot]", "dependabot*", "renovate*"}
+
+// Max number of pages (100 items each) to fetch from Github per repo
+var maxPages = 5
 
 //go:embed index.html
 var index string
 
 func getModesFromList(m []string) Modes {
-	modes := Modes{false, false, false, false}
+	modes := Modes{false, false, false, false, false, false, false}
+	merged := false
 	for _, entry := range m {
 		switch entry {
 		case "io":
@@ -33,11 +52,123 @@ func getModesFromList(m []string) Modes {
 			modes.PROpen = true
 		case "pc":
 			modes.PRClosed = true
+		case "pm":
+			modes.PRMerged = true
+			merged = true
+		case "ir":
+			modes.IssueReopened = true
+		case "pr":
+			modes.PRReopened = true
 		}
 	}
+	// pc used to include merged prs, keep that for older feeds
+	// unless merges are asked for separately
+	if modes.PRClosed && !merged {
+		modes.PRMerged = true
+	}
 	return modes
 }
 
+// Content types for each of the supported feed formats
+var feedContentTypes = map[string]string{
+	"rss":  "application/rss+xml; charset=utf-8",
+	"atom": "application/atom+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+}
+
+// getFormat figures out the feed format from the `f` query param, an
+// extension on the path (`/org/repo.atom`) or the Accept header, in
+// that order. It also returns the path with the extension removed.
+// The extension is left alone when `f` is given as repo names can
+// end in something like `.json` too.
+func getFormat(r *http.Request, path string) (string, string, error) {
+	if f := r.URL.Query().Get("f"); f != "" {
+		if _, ok := feedContentTypes[f]; !ok {
+			return "", path, errors.New("invalid format " + f + ", use one of [rss,atom,json]")
+		}
+		return f, path, nil
+	}
+
+	for f := range feedContentTypes {
+		if strings.HasSuffix(path, "."+f) {
+			return f, strings.TrimSuffix(path, "."+f), nil
+		}
+	}
+
+	return acceptFormat(r.Header.Get("Accept")), path, nil
+}
+
+// Media types in the Accept header which map to a feed format
+var acceptTypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+}
+
+// acceptFormat picks the feed format from an Accept header. Readers
+// usually list every format they understand, so rss (which is what we
+// always served) wins whenever it is acceptable. Otherwise we go with
+// the feed type with the highest q-value, falling back to rss.
+func acceptFormat(accept string) string {
+	format, best := "rss", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		f, ok := acceptTypes[strings.ToLower(strings.TrimSpace(params[0]))]
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				var err error
+				if q, err = strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err != nil {
+					q = 0
+				}
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+		if f == "rss" {
+			return "rss"
+		}
+		if q > best {
+			format, best = f, q
+		}
+	}
+	return format
+}
+
+var repoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// validRepo checks that `repo` looks like org/repo. Repos end up in
+// Github API urls as well as in cache keys (which can be file paths)
+// and so nothing else is let through.
+func validRepo(repo string) bool {
+	if !repoPattern.MatchString(repo) {
+		return false
+	}
+	for _, part := range strings.Split(repo, "/") {
+		if part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// boolParam checks if a switch like `nobots` is enabled. The param on
+// its own (`?nobots`) is enough to enable it.
+func boolParam(params url.Values, name string) bool {
+	_, ok := params[name]
+	if v := params.Get(name); v == "0" || v == "false" {
+		return false
+	}
+	return ok
+}
+
 func setupResponse(w *http.ResponseWriter, req *http.Request) {
 	(*w).Header().Set("Access-Control-Allow-Origin", "*")
 	(*w).Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -65,7 +196,7 @@ func getHandler(cacheTimeout time.Duration) func(http.ResponseWriter, *http.Requ
 		}
 		params := r.URL.Query()
 		m, ok := params["m"]
-		modes := Modes{true, true, true, true}
+		modes := Modes{true, true, true, true, true, true, true}
 		if ok {
 			modes = getModesFromList(m)
 		}
@@ -76,34 +207,202 @@ func getHandler(cacheTimeout time.Duration) func(http.ResponseWriter, *http.Requ
 			url = url[:len(url)-1]
 		}
 
-		splits := strings.Split(url, "/")
-		if len(splits) != 3 { // url starts with /
-			http.Error(w, "Invalid request: call `<url>/org/repo`", http.StatusBadRequest)
+		format, url, err := getFormat(r, url)
+		if err != nil {
+			http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var repo, search string
+		var repos []string
+		var issue int64
+		var releases bool
+		if url == "/search" {
+			// `q` is the Github search query here rather than a filter
+			search = params.Get("q")
+			if search == "" {
+				http.Error(w, "Invalid request: call `<url>/search?q=<github search query>`", http.StatusBadRequest)
+				return
+			}
+		} else if url == "/multi" {
+			repos = params["r"]
+			if len(repos) == 0 {
+				http.Error(w, "Invalid request: call `<url>/multi?r=org/repo&r=org/repo`", http.StatusBadRequest)
+				return
+			}
+			for _, r := range repos {
+				if !validRepo(r) {
+					http.Error(w, "Invalid request: invalid repo "+r+", use org/repo", http.StatusBadRequest)
+					return
+				}
+			}
+		} else {
+			splits := strings.Split(url, "/")
+
+			// `/org/repo/issues/<n>` is a feed of comments on the issue
+			if len(splits) == 5 && (splits[3] == "issues" || splits[3] == "pull") {
+				issue, err = strconv.ParseInt(splits[4], 10, 64)
+				if err != nil || issue <= 0 {
+					http.Error(w, "Invalid request: invalid issue number "+splits[4], http.StatusBadRequest)
+					return
+				}
+				splits = splits[:3]
+			}
+
+			// `/org/repo/releases` is a feed of releases
+			if len(splits) == 4 && splits[3] == "releases" {
+				releases = true
+				splits = splits[:3]
+			}
+
+			if len(splits) != 3 || !validRepo(splits[1]+"/"+splits[2]) { // url starts with /
+				http.Error(w, "Invalid request: call `<url>/org/repo`", http.StatusBadRequest)
+				return
+			}
+			repo = splits[1] + "/" + splits[2]
+		}
+
+		// `/_org/<org>` and `/_user/<login>` are feeds across all their
+		// repos. Github logins can't start with an underscore and so
+		// these can't clash with a repo.
+		var owner, ownerType string
+		var includeRepos, excludeRepos []string
+		splits := strings.Split(repo, "/")
+		if t := strings.TrimPrefix(splits[0], "_"); t != splits[0] && ownerTypes[t] != "" && issue == 0 && !releases {
+			ownerType, owner = t, splits[1]
+			includeRepos, excludeRepos = params["r"], params["nr"]
+			repo = ""
+		}
+
+		bodyFormat := params.Get("body")
+		if bodyFormat != "" && !isIn(bodyFormat, bodyFormats) {
+			http.Error(w, "Invalid request: invalid body format "+bodyFormat+", use one of [html,text,raw]", http.StatusBadRequest)
 			return
 		}
-		repo := splits[1] + "/" + splits[2]
+
+		title := params.Get("t")
+		if title != "" {
+			if _, ok := titlePresets[title]; !ok {
+				http.Error(w, "Invalid request: invalid title preset "+title+", use one of [default,numbered,repo]", http.StatusBadRequest)
+				return
+			}
+		}
 
 		labels := params["l"]
 		notlabels := params["nl"]
 		users := params["u"]
 		notusers := params["nu"]
+		text := params["s"]
+		nottext := params["ns"]
+		assignees := params["a"]
+		notassignees := params["na"]
+		milestones := params["ms"]
+		notmilestone := params["nms"]
+		assocs := params["aa"]
+		notassocs := params["naa"]
+
+		since := params.Get("since")
+		until := params.Get("until")
+		for _, t := range []string{since, until} {
+			if _, err := parseTimeBound(t, time.Now()); t != "" && err != nil {
+				http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		limit := 0
+		if l := params.Get("limit"); l != "" {
+			limit, err = strconv.Atoi(l)
+			if err != nil || limit < 0 {
+				http.Error(w, "Invalid request: invalid limit "+l, http.StatusBadRequest)
+				return
+			}
+		}
+
+		nobots := boolParam(params, "nobots")
+
+		comments := boolParam(params, "comments")
+		if comments && repo == "" {
+			http.Error(w, "Invalid request: comments are only supported for single repo feeds", http.StatusBadRequest)
+			return
+		}
+
+		query := params.Get("q")
+		if search != "" {
+			query = ""
+		}
 
 		rc := RunConfig{
-			Modes:     modes,
-			Labels:    labels,
-			NotLabels: notlabels,
-			Users:     users,
-			NotUsers:  notusers,
-			Repo:      repo,
+			Modes:      modes,
+			Labels:     labels,
+			NotLabels:  notlabels,
+			Users:      users,
+			NotUsers:   notusers,
+			Text:       text,
+			NotText:    nottext,
+			Query:      query,
+			Repo:       repo,
+			Repos:      repos,
+			Search:     search,
+			Issue:      issue,
+			Comments:   comments,
+			Releases:   releases,
+			Format:     format,
+			BodyFormat: bodyFormat,
+			Since:      since,
+			Until:      until,
+			Limit:      limit,
+
+			TitleTemplate: title,
+
+			Assignees:       assignees,
+			NotAssignees:    notassignees,
+			Milestones:      milestones,
+			NotMilestones:   notmilestone,
+			Associations:    assocs,
+			NotAssociations: notassocs,
+			NoBots:          nobots,
+
+			PreReleases: boolParam(params, "prereleases"),
+			Drafts:      boolParam(params, "drafts"),
+
+			Owner:        owner,
+			OwnerType:    ownerType,
+			IncludeRepos: includeRepos,
+			ExcludeRepos: excludeRepos,
+			Archived:     boolParam(params, "archived"),
+			Forks:        boolParam(params, "forks"),
+		}
+
+		if _, err := compileFilter(rc); err != nil {
+			http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		rss, err := getIssueFeed(rc, cacheTimeout)
+		feed, err := getIssueFeed(rc, cacheTimeout)
 		if err != nil {
-			http.Error(w, "Unable to fetch atom feed", http.StatusNotFound)
+			var rle *RateLimitError
+			if errors.As(err, &rle) {
+				retryAfter := int(time.Until(rle.Reset).Seconds()) + 1
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, "Rate limited by Github, try again later", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, "Unable to fetch feed", http.StatusNotFound)
 			return
 		}
+		if len(repos) > 0 {
+			repo = strings.Join(repos, ",")
+		}
+		if owner != "" {
+			repo = ownerType + " " + owner
+		}
+		if search != "" {
+			repo = "search " + search
+		}
 		fmt.Println(time.Now().Format("2006-01-02 15:04:05"), "[OK]", repo)
-		io.WriteString(w, rss)
+		w.Header().Set("Content-Type", feedContentTypes[format])
+		io.WriteString(w, feed)
 	}
 
 	return handler
@@ -116,32 +415,126 @@ func getCliArgs() (config, error) {
 		notlabels    string
 		users        string
 		notusers     string
+		text         string
+		nottext      string
+		assignees    string
+		notassignees string
+		milestones   string
+		notmilestone string
+		assocs       string
+		notassocs    string
+		nobots       bool
+		bots         string
+		org          string
+		user         string
+		repos        string
+		notrepos     string
+		archived     bool
+		forks        bool
+		search       string
+		comments     bool
+		issue        int64
+		releases     bool
+		prereleases  bool
+		drafts       bool
+		query        string
+		format       string
+		bodyFormat   string
+		since        string
+		until        string
+		limit        int
+		title        string
 		server       bool
 		port         int
 		cacheTimeout int64
+		cacheKind    string
+		cachePath    string
+		cacheSize    int
+		swr          bool
+		refresh      int64
 	)
 
-	flag.StringVar(&modes, "m", "", "Comma separated list of modes [io,ic,po,pc]")
-	flag.StringVar(&labels, "l", "", "Comma separated list of labels to include")
-	flag.StringVar(&notlabels, "nl", "", "Comma separated list of labels to exclude")
+	flag.StringVar(&modes, "m", "", "Comma separated list of modes [io,ic,ir,po,pc,pm,pr]")
+	flag.StringVar(&labels, "l", "", "Comma separated list of labels to include (globs, /regex/ and | for any of)")
+	flag.StringVar(&notlabels, "nl", "", "Comma separated list of labels to exclude (globs, /regex/ and | for any of)")
 	flag.StringVar(&users, "u", "", "Comma separated list of users to include")
 	flag.StringVar(&notusers, "nu", "", "Comma separated list of users to exclude")
+	flag.StringVar(&text, "s", "", "Comma separated list of text (or /regex/) to search for in title and body")
+	flag.StringVar(&nottext, "ns", "", "Comma separated list of text (or /regex/) to exclude on title and body")
+	flag.StringVar(&assignees, "a", "", "Comma separated list of assignees to include (unassigned for none)")
+	flag.StringVar(&notassignees, "na", "", "Comma separated list of assignees to exclude (unassigned for none)")
+	flag.StringVar(&milestones, "ms", "", "Comma separated list of milestones to include (none for no milestone)")
+	flag.StringVar(&notmilestone, "nms", "", "Comma separated list of milestones to exclude (none for no milestone)")
+	flag.StringVar(&assocs, "aa", "", "Comma separated list of author associations to include [member,contributor,first_time_contributor,...]")
+	flag.StringVar(&notassocs, "naa", "", "Comma separated list of author associations to exclude [member,contributor,first_time_contributor,...]")
+	flag.BoolVar(&nobots, "nobots", false, "Exclude issues and prs created by bots")
+	flag.StringVar(&org, "org", "", "Create feed across all repos of an org instead of a single repo")
+	flag.StringVar(&user, "user", "", "Create feed across all repos of a user instead of a single repo")
+	flag.StringVar(&search, "search", "", "Create feed from a Github issue search query instead of a repo")
+	flag.StringVar(&repos, "r", "", "Comma separated list of repo globs to include for -org and -user")
+	flag.StringVar(&notrepos, "nr", "", "Comma separated list of repo globs to exclude for -org and -user")
+	flag.BoolVar(&archived, "archived", false, "Include archived repos for -org and -user")
+	flag.BoolVar(&forks, "forks", false, "Include forked repos for -org and -user")
+	flag.BoolVar(&comments, "comments", false, "Create feed of comments on the issues of a repo instead of issue events")
+	flag.Int64Var(&issue, "issue", 0, "Create feed of comments on a single issue or pr of a repo")
+	flag.BoolVar(&releases, "releases", false, "Create feed of releases of a repo instead of issues")
+	flag.BoolVar(&prereleases, "prereleases", false, "Include pre-releases in release feed")
+	flag.BoolVar(&drafts, "drafts", false, "Include draft releases in release feed (needs a token with push access)")
+	flag.StringVar(&query, "q", "", "Filter expression, eg: (label:bug or label:regression) and not author:dependabot")
+	flag.StringVar(&format, "format", "", "Feed format [rss,atom,json] (default rss)")
+	flag.StringVar(&bodyFormat, "body", "", "How to render issue body [html,text,raw] (default html)")
+	flag.StringVar(&since, "since", "", "Only include events after this time, eg: 2021-09-08 or 7d")
+	flag.StringVar(&until, "until", "", "Only include events before this time, eg: 2021-09-08 or 7d")
+	flag.IntVar(&limit, "limit", 0, "Max number of items in the feed, newest first (0 for no limit)")
+	flag.StringVar(&title, "title-template", "", "Title template, either a preset [default,numbered,repo] or a Go template")
 	flag.BoolVar(&server, "server", false, "run as server instead of cli mode")
 	flag.IntVar(&port, "port", 0, "port to use for server")
 	flag.Int64Var(&cacheTimeout, "cache-timeout", 60*12, "cache timeout in minutes, 0 to disable")
+	flag.StringVar(&cacheKind, "cache", "file", "cache backend to use [file,memory,sqlite]")
+	flag.StringVar(&cachePath, "cache-path", "", "cache directory for file backend, database for sqlite backend")
+	flag.IntVar(&cacheSize, "cache-size", 1000, "max number of entries to keep in memory backend")
+	flag.BoolVar(&swr, "stale-while-revalidate", false, "serve expired cache and refresh it in background")
+	flag.Int64Var(&refresh, "refresh-interval", 0, "refresh recently requested repos in background every n minutes, 0 to disable")
+	flag.IntVar(&maxPages, "max-pages", 5, "max number of pages (100 items each) to fetch from Github")
+	flag.StringVar(&bots, "bot-logins", "", "Comma separated list of login patterns to treat as bots (default *[bot],dependabot*,renovate*)")
 
 	flag.Parse() // after declaring flags we need to call it
 
+	if bots != "" {
+		botLogins = strings.Split(bots, ",")
+	}
+
+	if title != "" {
+		if _, err := parseTitleTemplate(title); err != nil {
+			return config{}, errors.New("invalid title template: " + err.Error())
+		}
+	}
+
 	if server {
-		return config{ServerConfig: &ServerConfig{port, cacheTimeout}}, nil
+		return config{ServerConfig: &ServerConfig{
+			Port:         port,
+			CacheTimeout: cacheTimeout,
+			Cache:        cacheKind,
+			CachePath:    cachePath,
+			CacheSize:    cacheSize,
+
+			TitleTemplate: title,
+
+			StaleWhileRevalidate: swr,
+			RefreshInterval:      refresh,
+		}}, nil
 	}
 
-	if len(flag.Args()) != 1 {
+	if org != "" && user != "" {
+		return config{}, errors.New("only one of -org and -user can be used")
+	}
+
+	if len(flag.Args()) == 0 && org == "" && user == "" && search == "" {
 		return config{}, errors.New("need repo when not running in server mode")
 	}
 
 	cfg := config{RunConfig: &RunConfig{
-		Modes: Modes{true, true, true, true}, // default should be all true
+		Modes: Modes{true, true, true, true, true, true, true}, // default should be all true
 	}}
 
 	if modes != "" {
@@ -149,11 +542,11 @@ func getCliArgs() (config, error) {
 	}
 
 	if labels != "" { // prevents empty "" item
-		cfg.RunConfig.Labels = strings.Split(labels, ",")
+		cfg.RunConfig.Labels = splitPatterns(labels)
 	}
 
 	if notlabels != "" {
-		cfg.RunConfig.NotLabels = strings.Split(notlabels, ",")
+		cfg.RunConfig.NotLabels = splitPatterns(notlabels)
 	}
 
 	if users != "" {
@@ -164,34 +557,252 @@ func getCliArgs() (config, error) {
 		cfg.RunConfig.NotUsers = strings.Split(notusers, ",")
 	}
 
-	cfg.RunConfig.Repo = flag.Args()[0]
+	if text != "" {
+		cfg.RunConfig.Text = splitPatterns(text)
+	}
+
+	if nottext != "" {
+		cfg.RunConfig.NotText = splitPatterns(nottext)
+	}
+
+	if assignees != "" {
+		cfg.RunConfig.Assignees = strings.Split(assignees, ",")
+	}
+
+	if notassignees != "" {
+		cfg.RunConfig.NotAssignees = strings.Split(notassignees, ",")
+	}
+
+	if milestones != "" {
+		cfg.RunConfig.Milestones = strings.Split(milestones, ",")
+	}
+
+	if notmilestone != "" {
+		cfg.RunConfig.NotMilestones = strings.Split(notmilestone, ",")
+	}
+
+	if assocs != "" {
+		cfg.RunConfig.Associations = strings.Split(assocs, ",")
+	}
+
+	if notassocs != "" {
+		cfg.RunConfig.NotAssociations = strings.Split(notassocs, ",")
+	}
+
+	cfg.RunConfig.NoBots = nobots
+
+	if query != "" {
+		cfg.RunConfig.Query = query
+	}
+
+	if format != "" {
+		if _, ok := feedContentTypes[format]; !ok {
+			return config{}, errors.New("invalid format " + format + ", use one of [rss,atom,json]")
+		}
+		cfg.RunConfig.Format = format
+	}
+
+	if bodyFormat != "" {
+		if !isIn(bodyFormat, bodyFormats) {
+			return config{}, errors.New("invalid body format " + bodyFormat + ", use one of [html,text,raw]")
+		}
+		cfg.RunConfig.BodyFormat = bodyFormat
+	}
+
+	for _, t := range []string{since, until} {
+		if _, err := parseTimeBound(t, time.Now()); t != "" && err != nil {
+			return config{}, err
+		}
+	}
+	cfg.RunConfig.Since = since
+	cfg.RunConfig.Until = until
+
+	if limit < 0 {
+		return config{}, errors.New("invalid limit " + strconv.Itoa(limit))
+	}
+	cfg.RunConfig.Limit = limit
+
+	cfg.RunConfig.TitleTemplate = title
+
+	if issue < 0 {
+		return config{}, errors.New("invalid issue number " + strconv.FormatInt(issue, 10))
+	}
+	cfg.RunConfig.Comments = comments
+	cfg.RunConfig.Issue = issue
+	if (comments || issue != 0) && (len(flag.Args()) != 1 || search != "" || org != "" || user != "") {
+		return config{}, errors.New("-comments and -issue only work with a single repo")
+	}
+	cfg.RunConfig.Releases = releases
+	cfg.RunConfig.PreReleases = prereleases
+	cfg.RunConfig.Drafts = drafts
+	if releases && (len(flag.Args()) != 1 || search != "" || org != "" || user != "") {
+		return config{}, errors.New("-releases only works with a single repo")
+	}
+
+	if search != "" {
+		if len(flag.Args()) != 0 || org != "" || user != "" {
+			return config{}, errors.New("can't pass repos, -org or -user along with -search")
+		}
+		cfg.RunConfig.Search = search
+	} else if org != "" || user != "" {
+		if len(flag.Args()) != 0 {
+			return config{}, errors.New("can't pass repos along with -org or -user")
+		}
+		cfg.RunConfig.Owner, cfg.RunConfig.OwnerType = org, "org"
+		if user != "" {
+			cfg.RunConfig.Owner, cfg.RunConfig.OwnerType = user, "user"
+		}
+		if repos != "" {
+			cfg.RunConfig.IncludeRepos = strings.Split(repos, ",")
+		}
+		if notrepos != "" {
+			cfg.RunConfig.ExcludeRepos = strings.Split(notrepos, ",")
+		}
+		cfg.RunConfig.Archived = archived
+		cfg.RunConfig.Forks = forks
+	} else if len(flag.Args()) == 1 {
+		cfg.RunConfig.Repo = flag.Args()[0]
+	} else {
+		cfg.RunConfig.Repos = flag.Args()
+	}
+
+	if _, err := compileFilter(*cfg.RunConfig); err != nil {
+		return config{}, err
+	}
 
 	return cfg, nil
 }
 
+// splitPatterns splits a comma separated list of label or text patterns
+// while keeping commas within regexes like `/^p[0-9]{1,2}$/` intact
+func splitPatterns(patterns string) []string {
+	var split []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		last := len(split) - 1
+		if last >= 0 && strings.HasPrefix(split[last], "/") &&
+			(len(split[last]) == 1 || !strings.HasSuffix(split[last], "/")) {
+			split[last] += "," + pattern
+			continue
+		}
+		split = append(split, pattern)
+	}
+	return split
+}
+
 // A better version of flag.Usage
 func printHelp() {
-	fmt.Println(path.Base(os.Args[0]) + ` [FLAGS] [repo] [--server]
+	fmt.Println(path.Base(os.Args[0]) + ` [FLAGS] [repo...] [--server]
 
 Server mode (use -server to switch to server mode):
   -port int
         port to use for server (default 8080)
   -cache-timeout float
         cache timeout in minutes, 0 to disable (default: 12 hours)
+  -cache string
+        cache backend to use [file,memory,sqlite] (default "file")
+  -cache-path string
+        cache directory for file backend, database for sqlite backend
+        (default /tmp/gh-issues-to-rss-cache and /tmp/gh-issues-to-rss-cache.db)
+  -cache-size int
+        max number of entries to keep in memory backend (default 1000)
+  -stale-while-revalidate
+        serve expired cache and refresh it in background
+  -refresh-interval int
+        refresh recently requested repos in background every n minutes, 0 to disable
 Example: ` + path.Base(os.Args[0]) + ` -server -port 8080 -cache-timeout 720
 
+Common:
+  -max-pages int
+        max number of pages (100 items each) to fetch from Github (default 5)
+  -bot-logins string
+        Comma separated list of login patterns to treat as bots in addition to
+        accounts and apps Github marks as bots (default *[bot],dependabot*,renovate*)
+  -title-template string
+        Title template, either a preset [default,numbered,repo] or a Go template
+        with .Repo, .Number, .Type, .Event, .Title, .Labels and .Author
+        (default "default"; sets the default for all feeds in server mode)
+
 Single repo mode:
   -m string
-        Comma separated list of modes [io,ic,po,pc]
+        Comma separated list of modes [io,ic,ir,po,pc,pm,pr]
   -l string
-        Comma separated list of labels to include
+        Comma separated list of labels to include (globs, /regex/ and | for any of)
   -nl string
-        Comma separated list of labels to exclude
+        Comma separated list of labels to exclude (globs, /regex/ and | for any of)
   -u string
         Comma separated list of users to include
   -nu string
         Comma separated list of users to exclude
-Example: ` + path.Base(os.Args[0]) + ` -m io,ic,po,pc -l bug,enhancement -nl invalid -u user1,user2 -nu user3,user4 org/repo`)
+  -s string
+        Comma separated list of text (or /regex/) to search for in title and body
+  -ns string
+        Comma separated list of text (or /regex/) to exclude on title and body
+  -a string
+        Comma separated list of assignees to include (unassigned for none)
+  -na string
+        Comma separated list of assignees to exclude (unassigned for none)
+  -ms string
+        Comma separated list of milestones to include (none for no milestone)
+  -nms string
+        Comma separated list of milestones to exclude (none for no milestone)
+  -aa string
+        Comma separated list of author associations to include [member,contributor,first_time_contributor,...]
+  -naa string
+        Comma separated list of author associations to exclude [member,contributor,first_time_contributor,...]
+  -nobots
+        Exclude issues and prs created by bots
+  -q string
+        Filter expression, eg: (label:bug or label:regression) and not author:dependabot
+  -format string
+        Feed format [rss,atom,json] (default rss)
+  -body string
+        How to render issue body [html,text,raw] (default html)
+  -since string
+        Only include events after this time, eg: 2021-09-08 or 7d
+  -until string
+        Only include events before this time, eg: 2021-09-08 or 7d
+  -limit int
+        Max number of items in the feed, newest first (0 for no limit)
+Example: ` + path.Base(os.Args[0]) + ` -m io,ic,po,pc,pm -l bug,enhancement -nl invalid -u user1,user2 -nu user3,user4 org/repo
+Passing multiple repos creates a single feed combining all of them.
+
+Comment mode (single repo only):
+  -comments
+        Create feed of comments on the issues of a repo instead of issue events
+  -issue int
+        Create feed of comments on a single issue or pr of a repo
+Filters apply to the issues the comments are on, -nobots also skips bot comments.
+Example: ` + path.Base(os.Args[0]) + ` -issue 42 org/repo
+
+Release mode (single repo only):
+  -releases
+        Create feed of releases of a repo instead of issues
+  -prereleases
+        Include pre-releases in release feed
+  -drafts
+        Include draft releases in release feed (needs a token with push access)
+Issue filters do not apply, -format, -body, -since, -until and -limit do.
+Example: ` + path.Base(os.Args[0]) + ` -releases -prereleases org/repo
+
+Org and user mode (instead of passing repos):
+  -org string
+        Create feed across all repos of an org instead of a single repo
+  -user string
+        Create feed across all repos of a user instead of a single repo
+  -r string
+        Comma separated list of repo globs to include for -org and -user
+  -nr string
+        Comma separated list of repo globs to exclude for -org and -user
+  -archived
+        Include archived repos for -org and -user
+  -forks
+        Include forked repos for -org and -user
+Example: ` + path.Base(os.Args[0]) + ` -org nvim-treesitter -nr '*-archive' -m io
+
+Search mode (instead of passing repos):
+  -search string
+        Create feed from a Github issue search query instead of a repo
+Example: ` + path.Base(os.Args[0]) + ` -search 'is:pr is:open review-requested:@me'`)
 }
 
 func main() {
@@ -205,12 +816,35 @@ func main() {
 	}
 
 	if cfg.RunConfig != nil {
-		atom, err := getIssueFeed(*cfg.RunConfig, 0)
+		feed, err := getIssueFeed(*cfg.RunConfig, 0)
 		if err != nil {
-			log.Fatal("Unable to create feed for repo", cfg.RunConfig.Repo, ":", err)
+			repo := cfg.RunConfig.Repo
+			if len(cfg.RunConfig.Repos) > 0 {
+				repo = strings.Join(cfg.RunConfig.Repos, ",")
+			}
+			if cfg.RunConfig.Owner != "" {
+				repo = cfg.RunConfig.Owner
+			}
+			if cfg.RunConfig.Search != "" {
+				repo = cfg.RunConfig.Search
+			}
+			log.Fatal("Unable to create feed for repo ", repo, ": ", err)
 		}
-		fmt.Println(atom)
+		fmt.Println(feed)
 	} else {
+		cache, err = newCacheBackend(cfg.ServerConfig.Cache, cfg.ServerConfig.CachePath, cfg.ServerConfig.CacheSize)
+		if err != nil {
+			log.Fatal("Unable to setup cache: ", err)
+		}
+
+		staleWhileRevalidate = cfg.ServerConfig.StaleWhileRevalidate
+		if cfg.ServerConfig.TitleTemplate != "" {
+			titleTemplate = cfg.ServerConfig.TitleTemplate
+		}
+		if cfg.ServerConfig.RefreshInterval > 0 {
+			startRefresher(time.Duration(cfg.ServerConfig.RefreshInterval) * time.Minute)
+		}
+
 		http.HandleFunc("/", getHandler(time.Duration(cfg.ServerConfig.CacheTimeout)*time.Minute))
 
 		port := ":" + strconv.Itoa(cfg.ServerConfig.Port)
@@ -4,9 +4,11 @@ import (
 	"flag"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"gopkg.in/h2non/gock.v1"
@@ -25,10 +27,262 @@ func TestWebserverPing(t *testing.T) {
 
 }
 
+func TestWebserverRateLimited(t *testing.T) {
+	cacheLocationBackup := cacheLocation
+	defer func() { cacheLocation = cacheLocationBackup }()
+	dir, err := os.MkdirTemp("", "gh-issues-to-rss")
+	if err != nil {
+		t.Fatalf("Unable to create temp directory: %s", err)
+	}
+	cacheLocation = dir
+
+	rateLimitedUntil["core"] = time.Now().Add(time.Hour)
+	defer func() { rateLimitedUntil = map[string]time.Time{} }()
+
+	request, _ := http.NewRequest(http.MethodGet, "/meain/dotfiles", nil)
+	response := httptest.NewRecorder()
+	handler := getHandler(0)
+	handler(response, request)
+
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %v, got %v", http.StatusServiceUnavailable, response.Code)
+	}
+	if response.Header().Get("Retry-After") == "" {
+		t.Fatalf("Retry-After header not set")
+	}
+}
+
+func TestWebserverFormats(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	data := []GithubIssue{
+		GithubIssue{
+			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
+			Title:     "Sample Entry",
+			HTMLURL:   "https://example.com",
+			Body:      "Some body",
+		},
+	}
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos").
+		Reply(200).
+		JSON(data)
+
+	table := []struct {
+		name        string
+		url         string
+		accept      string
+		contentType string
+		content     string
+	}{
+		{"default", "/meain/dotfiles", "", "application/rss+xml; charset=utf-8", "<rss"},
+		{"extension", "/meain/dotfiles.atom", "", "application/atom+xml; charset=utf-8", "<feed"},
+		{"extension with slash", "/meain/dotfiles.json/", "", "application/feed+json; charset=utf-8", `"version": "https://jsonfeed.org/version/1"`},
+		{"query", "/meain/dotfiles?f=json", "", "application/feed+json; charset=utf-8", `"items"`},
+		{"accept", "/meain/dotfiles", "application/atom+xml", "application/atom+xml; charset=utf-8", "<feed"},
+		{"accept rss", "/meain/dotfiles", "application/rss+xml, application/atom+xml;q=0.6, */*;q=0.1", "application/rss+xml; charset=utf-8", "<rss"},
+		{"accept json", "/meain/dotfiles", "application/json", "application/rss+xml; charset=utf-8", "<rss"},
+	}
+
+	handler := getHandler(time.Hour)
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.accept != "" {
+				request.Header.Set("Accept", tc.accept)
+			}
+			response := httptest.NewRecorder()
+			handler(response, request)
+
+			if response.Code != http.StatusOK {
+				t.Fatalf("Invalid status code %v: %s", response.Code, response.Body.String())
+			}
+			if got := response.Header().Get("Content-Type"); got != tc.contentType {
+				t.Fatalf("Invalid content type, expected %v, got %v", tc.contentType, got)
+			}
+			if !strings.Contains(response.Body.String(), tc.content) {
+				t.Fatalf("Feed is not in the right format: %s", response.Body.String())
+			}
+		})
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "/meain/dotfiles?f=yaml", nil)
+	response := httptest.NewRecorder()
+	handler(response, request)
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request for invalid format, got %v", response.Code)
+	}
+}
+
+func TestGetFormat(t *testing.T) {
+	table := []struct {
+		url    string
+		format string
+		path   string
+	}{
+		{"/meain/dotfiles", "rss", "/meain/dotfiles"},
+		{"/meain/dotfiles.atom", "atom", "/meain/dotfiles"},
+		{"/meain/dotfiles?f=json", "json", "/meain/dotfiles"},
+		{"/meain/feeds.json?f=rss", "rss", "/meain/feeds.json"},
+		{"/meain/dotfiles.rss?f=atom", "atom", "/meain/dotfiles.rss"},
+	}
+
+	for _, tc := range table {
+		request, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+		format, path, err := getFormat(request, request.URL.Path)
+		if err != nil {
+			t.Fatalf("Unable to get format for %v: %s", tc.url, err)
+		}
+		if format != tc.format || path != tc.path {
+			t.Fatalf("Invalid format for %v, expected %v %v, got %v %v", tc.url, tc.format, tc.path, format, path)
+		}
+	}
+}
+
+func TestAcceptFormat(t *testing.T) {
+	table := []struct {
+		accept string
+		format string
+	}{
+		{"", "rss"},
+		{"*/*", "rss"},
+		{"application/atom+xml", "atom"},
+		{"application/feed+json", "json"},
+		{"application/json", "rss"},
+		{"application/rss+xml, application/atom+xml;q=0.6, */*;q=0.1", "rss"},
+		{"application/atom+xml;q=0.6, application/rss+xml;q=0.5", "rss"},
+		{"application/rss+xml;q=0, application/atom+xml", "atom"},
+		{"application/atom+xml;q=0.5, application/feed+json", "json"},
+		{"Application/Atom+XML ; q=0.9", "atom"},
+		{"application/atom+xml;q=0", "rss"},
+	}
+
+	for _, tc := range table {
+		if got := acceptFormat(tc.accept); got != tc.format {
+			t.Errorf("Expected %v for %q, got %v", tc.format, tc.accept, got)
+		}
+	}
+}
+
+func TestWebserverFilters(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	data := []GithubIssue{
+		GithubIssue{
+			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
+			Title:     "Sample Entry",
+			HTMLURL:   "https://example.com",
+			User:      GithubUser{Login: "meain", Type: "User"},
+		},
+		GithubIssue{
+			CreatedAt: "2021-09-10T12:44:47Z",
+			Number:    2,
+			Title:     "Bump dependency",
+			HTMLURL:   "https://example.com",
+			User:      GithubUser{Login: "dependabot[bot]", Type: "Bot"},
+		},
+	}
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos").
+		Reply(200).
+		JSON(data)
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		Reply(200).
+		BodyString(`{"total_count":1,"items":[{"number":1,"title":"Found Entry","created_at":"2021-09-08T12:44:47Z","repository_url":"https://api.github.com/repos/meain/dotfiles"}]}`)
+	gock.New("https://api.github.com").
+		Get("/orgs/meain/repos").
+		Reply(200).
+		BodyString(`[{"name":"dotfiles","full_name":"meain/dotfiles"},{"name":"other","full_name":"meain/other"}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/1$").
+		Reply(200).
+		BodyString(`{"number":1,"title":"Sample Entry","html_url":"https://github.com/meain/dotfiles/issues/1"}`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/1/comments$").
+		Reply(200).
+		BodyString(`[{"body":"A comment","html_url":"https://github.com/meain/dotfiles/issues/1#issuecomment-1","issue_url":"https://api.github.com/repos/meain/dotfiles/issues/1","created_at":"2021-09-09T12:44:47Z","user":{"login":"meain"}}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues/comments$").
+		Reply(200).
+		BodyString(`[{"body":"Rebased","html_url":"https://github.com/meain/dotfiles/issues/2#issuecomment-2","issue_url":"https://api.github.com/repos/meain/dotfiles/issues/2","created_at":"2021-09-11T12:44:47Z","user":{"login":"dependabot[bot]","type":"Bot"}}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/releases$").
+		Reply(200).
+		BodyString(`[{"name":"v1.1.0-rc1","prerelease":true,"published_at":"2021-09-09T12:44:47Z"},{"name":"v1.0.0","published_at":"2021-09-08T12:44:47Z"}]`)
+
+	table := []struct {
+		name     string
+		url      string
+		code     int
+		contains string
+		excludes string
+	}{
+		{"all", "/meain/dotfiles", http.StatusOK, "Bump dependency", ""},
+		{"nobots", "/meain/dotfiles?nobots", http.StatusOK, "Sample Entry", "Bump dependency"},
+		{"nobots disabled", "/meain/dotfiles?nobots=false", http.StatusOK, "Bump dependency", ""},
+		{"query", "/meain/dotfiles?q=title~Bump", http.StatusOK, "Bump dependency", "Sample Entry"},
+		{"invalid query", "/meain/dotfiles?q=(label:bug", http.StatusBadRequest, "", ""},
+		{"invalid label regex", "/meain/dotfiles?l=/(/", http.StatusBadRequest, "", ""},
+		{"invalid association", "/meain/dotfiles?aa=stranger", http.StatusBadRequest, "", ""},
+		{"multi", "/multi?r=meain/dotfiles", http.StatusOK, "[meain/dotfiles] [issue-open]: Sample Entry", ""},
+		{"multi without repos", "/multi", http.StatusBadRequest, "", ""},
+		{"multi with invalid repo", "/multi?r=meain", http.StatusBadRequest, "", ""},
+		{"multi with path traversal", "/multi?r=../..", http.StatusBadRequest, "", ""},
+		{"multi with query in repo", "/multi?r=" + url.QueryEscape("a/b?state=open&x="), http.StatusBadRequest, "", ""},
+		{"org", "/_org/meain?nr=other", http.StatusOK, "[meain/dotfiles] [issue-open]: Sample Entry", ""},
+		{"repo of user named org", "/org/meain", http.StatusNotFound, "", ""},
+		{"search", "/search?q=is:issue", http.StatusOK, "<title>Search: is:issue</title>", ""},
+		{"search without query", "/search", http.StatusBadRequest, "", ""},
+		{"issue comments", "/meain/dotfiles/issues/1", http.StatusOK, "<title>meain/dotfiles#1: Sample Entry</title>", ""},
+		{"pr comments", "/meain/dotfiles/pull/1", http.StatusOK, "[issue-comment]: Sample Entry", ""},
+		{"repo comments", "/meain/dotfiles?comments&nobots", http.StatusOK, "<title>meain/dotfiles comments</title>", "Bump dependency"},
+		{"invalid issue", "/meain/dotfiles/issues/abc", http.StatusBadRequest, "", ""},
+		{"releases", "/meain/dotfiles/releases", http.StatusOK, "<title>[release-published]: v1.0.0</title>", "v1.1.0-rc1"},
+		{"prereleases", "/meain/dotfiles/releases?prereleases", http.StatusOK, "<title>[release-prerelease]: v1.1.0-rc1</title>", ""},
+		{"releases of repo named org", "/org/meain/releases", http.StatusNotFound, "", ""},
+		{"comments for org", "/_org/meain?comments", http.StatusBadRequest, "", ""},
+		{"limit", "/meain/dotfiles?limit=1", http.StatusOK, "<item>", "Sample Entry"},
+		{"since", "/meain/dotfiles?since=2021-09-09", http.StatusOK, "Bump dependency", "Sample Entry"},
+		{"until", "/meain/dotfiles?until=2021-09-09", http.StatusOK, "Sample Entry", "Bump dependency"},
+		{"invalid since", "/meain/dotfiles?since=yesterday", http.StatusBadRequest, "", ""},
+		{"invalid limit", "/meain/dotfiles?limit=-1", http.StatusBadRequest, "", ""},
+	}
+
+	handler := getHandler(time.Hour)
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			response := httptest.NewRecorder()
+			handler(response, request)
+
+			if response.Code != tc.code {
+				t.Fatalf("Expected status %v, got %v: %s", tc.code, response.Code, response.Body.String())
+			}
+			got := response.Body.String()
+			if tc.contains != "" && !strings.Contains(got, tc.contains) {
+				t.Fatalf("Expected %v in feed: %s", tc.contains, got)
+			}
+			if tc.excludes != "" && strings.Contains(got, tc.excludes) {
+				t.Fatalf("Did not expect %v in feed: %s", tc.excludes, got)
+			}
+		})
+	}
+}
+
 func TestFetchRssAll(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
@@ -37,6 +291,7 @@ func TestFetchRssAll(t *testing.T) {
 			CreatedAt: "2021-09-08T12:44:47Z",
 			ClosedAt:  "2021-10-08T12:44:47Z",
 			State:     "closed",
+			Number:    2,
 			Title:     "Another Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Another body",
@@ -61,22 +316,25 @@ func TestFetchRssAll(t *testing.T) {
 	rssContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-closed]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#closed-2021-10-08T12:44:47Z</guid>
       <pubDate>Fri, 08 Oct 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-open]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
   </channel>
@@ -92,6 +350,7 @@ func TestFetchRssOpenOnly(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
@@ -100,6 +359,7 @@ func TestFetchRssOpenOnly(t *testing.T) {
 			CreatedAt: "2021-09-08T12:44:47Z",
 			ClosedAt:  "2021-10-08T12:44:47Z",
 			State:     "closed",
+			Number:    2,
 			Title:     "Another Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Another body",
@@ -124,15 +384,17 @@ func TestFetchRssOpenOnly(t *testing.T) {
 	rssContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
     <item>
       <title>[issue-open]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>
   </channel>
@@ -148,6 +410,7 @@ func TestFetchRssWithGoodFirstLabel(t *testing.T) {
 	data := []GithubIssue{
 		GithubIssue{
 			CreatedAt: "2021-09-08T12:44:47Z",
+			Number:    1,
 			Title:     "Sample Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Some body",
@@ -157,6 +420,7 @@ func TestFetchRssWithGoodFirstLabel(t *testing.T) {
 			CreatedAt: "2021-09-08T12:44:47Z",
 			ClosedAt:  "2021-10-08T12:44:47Z",
 			State:     "closed",
+			Number:    2,
 			Title:     "Another Entry",
 			HTMLURL:   "https://example.com",
 			Body:      "Another body",
@@ -181,16 +445,19 @@ func TestFetchRssWithGoodFirstLabel(t *testing.T) {
 	shouldContent := `    <item>
       <title>[issue-open]: Sample Entry</title>
       <link>https://example.com</link>
-      <description>Some body</description>
-      <content:encoded><![CDATA[Some body]]></content:encoded>
+      <description>&lt;p&gt;Some body&lt;/p&gt;&lt;hr&gt;&lt;ul&gt;&lt;li&gt;&lt;strong&gt;Labels:&lt;/strong&gt; good-first-issue&lt;/li&gt;&lt;/ul&gt;</description>
+      <content:encoded><![CDATA[<p>Some body</p><hr><ul><li><strong>Labels:</strong> good-first-issue</li></ul>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/1#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
+      <category>good-first-issue</category>
     </item>`
 
 	shouldntContent := `    <item>
       <title>[issue-open]: Another Entry</title>
       <link>https://example.com</link>
-      <description>Another body</description>
-      <content:encoded><![CDATA[Another body]]></content:encoded>
+      <description>&lt;p&gt;Another body&lt;/p&gt;</description>
+      <content:encoded><![CDATA[<p>Another body</p>]]></content:encoded>
+      <guid>https://github.com/meain/dotfiles/issues/2#open</guid>
       <pubDate>Wed, 08 Sep 2021 12:44:47 +0000</pubDate>
     </item>`
 
@@ -217,15 +484,15 @@ func TestFetchRssWithGoodFirstLabel(t *testing.T) {
 // 		notusers  []string
 // 		server    bool
 // 	}{
-// 		{"simple", []string{"meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true}, nil, nil, nil, nil, false},
-// 		{"with-labels", []string{"-l", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true}, []string{"good-first-issue"}, nil, nil, nil, false},
-// 		{"with-modes", []string{"-m", "ic,po", "meain/dotfiles"}, "meain/dotfiles", Modes{false, true, true, false}, nil, nil, nil, nil, false},
-// 		{"with-modes-and-labels", []string{"-m", "ic,po", "-l", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{false, true, true, false}, []string{"good-first-issue"}, nil, nil, nil, false},
-// 		{"with-not-labels", []string{"-nl", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true}, nil, []string{"good-first-issue"}, nil, nil, false},
-// 		{"with-users-and-not-users", []string{"-u", "meain", "-nu", "niaem", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true}, nil, nil, []string{"meain"}, []string{"niaem"}, false},
+// 		{"simple", []string{"meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true, true, true, true}, nil, nil, nil, nil, false},
+// 		{"with-labels", []string{"-l", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true, true, true, true}, []string{"good-first-issue"}, nil, nil, nil, false},
+// 		{"with-modes", []string{"-m", "ic,po", "meain/dotfiles"}, "meain/dotfiles", Modes{false, true, true, false, false, false, false}, nil, nil, nil, nil, false},
+// 		{"with-modes-and-labels", []string{"-m", "ic,po", "-l", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{false, true, true, false, false, false, false}, []string{"good-first-issue"}, nil, nil, nil, false},
+// 		{"with-not-labels", []string{"-nl", "good-first-issue", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true, true, true, true}, nil, []string{"good-first-issue"}, nil, nil, false},
+// 		{"with-users-and-not-users", []string{"-u", "meain", "-nu", "niaem", "meain/dotfiles"}, "meain/dotfiles", Modes{true, true, true, true, true, true, true}, nil, nil, []string{"meain"}, []string{"niaem"}, false},
 
 // 	  // server
-// 		{"server", []string{"--server"}, "", Modes{true, true, true, true}, nil, nil, nil, nil, true},
+// 		{"server", []string{"--server"}, "", Modes{true, true, true, true, true, true, true}, nil, nil, nil, nil, true},
 // 	}
 // 	for _, tc := range tests {
 // 		t.Run(tc.name, func(t *testing.T) {
@@ -279,7 +546,7 @@ func TestCliFlagParsing(t *testing.T) {
 			cfg: config{
 				RunConfig: &RunConfig{
 					Repo:  "meain/dotfiles",
-					Modes: Modes{true, true, true, true},
+					Modes: Modes{true, true, true, true, true, true, true},
 				},
 			},
 		},
@@ -289,7 +556,7 @@ func TestCliFlagParsing(t *testing.T) {
 			cfg: config{
 				RunConfig: &RunConfig{
 					Repo:      "meain/dotfiles",
-					Modes:     Modes{false, true, true, false},
+					Modes:     Modes{false, true, true, false, false, false, false},
 					Labels:    []string{"good-first-issue"},
 					NotLabels: []string{"bug"},
 					Users:     []string{"meain"},
@@ -303,7 +570,7 @@ func TestCliFlagParsing(t *testing.T) {
 			cfg: config{
 				RunConfig: &RunConfig{
 					Repo:      "meain/dotfiles",
-					Modes:     Modes{false, true, true, false},
+					Modes:     Modes{false, true, true, false, false, false, false},
 					Labels:    []string{"good-first-issue", "p0"},
 					NotLabels: []string{"bug", "documentation"},
 					Users:     []string{"meain", "ain"},
@@ -311,6 +578,185 @@ func TestCliFlagParsing(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "with label patterns",
+			input: "-l bug|regression,/^p[0-9]{1,2}$/,area/* -nl /a,b/ meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:      "meain/dotfiles",
+					Modes:     Modes{true, true, true, true, true, true, true},
+					Labels:    []string{"bug|regression", "/^p[0-9]{1,2}$/", "area/*"},
+					NotLabels: []string{"/a,b/"},
+				},
+			},
+		},
+		{
+			name:  "with text",
+			input: "-s arm64,/linux|darwin/ -ns wip meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:    "meain/dotfiles",
+					Modes:   Modes{true, true, true, true, true, true, true},
+					Text:    []string{"arm64", "/linux|darwin/"},
+					NotText: []string{"wip"},
+				},
+			},
+		},
+		{
+			name:  "with assignees, milestones and associations",
+			input: "-a meain -na unassigned -ms v1.0,v1.1 -nms none -aa first_time_contributor -naa member,owner meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:            "meain/dotfiles",
+					Modes:           Modes{true, true, true, true, true, true, true},
+					Assignees:       []string{"meain"},
+					NotAssignees:    []string{"unassigned"},
+					Milestones:      []string{"v1.0", "v1.1"},
+					NotMilestones:   []string{"none"},
+					Associations:    []string{"first_time_contributor"},
+					NotAssociations: []string{"member", "owner"},
+				},
+			},
+		},
+		{
+			name:  "without bots",
+			input: "-nobots meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:   "meain/dotfiles",
+					Modes:  Modes{true, true, true, true, true, true, true},
+					NoBots: true,
+				},
+			},
+		},
+		{
+			name:  "with limits",
+			input: "-since 7d -until 2021-09-08 -limit 20 meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:  "meain/dotfiles",
+					Modes: Modes{true, true, true, true, true, true, true},
+					Since: "7d",
+					Until: "2021-09-08",
+					Limit: 20,
+				},
+			},
+		},
+		{
+			name:  "multiple repos",
+			input: "-m io meain/dotfiles meain/gh-issues-to-rss",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repos: []string{"meain/dotfiles", "meain/gh-issues-to-rss"},
+					Modes: Modes{true, false, false, false, false, false, false},
+				},
+			},
+		},
+		{
+			name:  "org",
+			input: "-org meain -r dot*,gh-* -nr *-archive -forks",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Owner:        "meain",
+					OwnerType:    "org",
+					Modes:        Modes{true, true, true, true, true, true, true},
+					IncludeRepos: []string{"dot*", "gh-*"},
+					ExcludeRepos: []string{"*-archive"},
+					Forks:        true,
+				},
+			},
+		},
+		{
+			name:  "user",
+			input: "-user meain -archived",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Owner:     "meain",
+					OwnerType: "user",
+					Modes:     Modes{true, true, true, true, true, true, true},
+					Archived:  true,
+				},
+			},
+		},
+		{
+			name:  "search",
+			input: "-search is:pr -m po",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Search: "is:pr",
+					Modes:  Modes{false, false, true, false, false, false, false},
+				},
+			},
+		},
+		{
+			name:  "comments",
+			input: "-comments -l bug meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:     "meain/dotfiles",
+					Modes:    Modes{true, true, true, true, true, true, true},
+					Labels:   []string{"bug"},
+					Comments: true,
+				},
+			},
+		},
+		{
+			name:  "issue comments",
+			input: "-issue 42 meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:  "meain/dotfiles",
+					Modes: Modes{true, true, true, true, true, true, true},
+					Issue: 42,
+				},
+			},
+		},
+		{
+			name:  "releases",
+			input: "-releases -prereleases meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:        "meain/dotfiles",
+					Modes:       Modes{true, true, true, true, true, true, true},
+					Releases:    true,
+					PreReleases: true,
+				},
+			},
+		},
+		{
+			name:  "with query",
+			input: "-q label:bug meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:  "meain/dotfiles",
+					Modes: Modes{true, true, true, true, true, true, true},
+					Query: "label:bug",
+				},
+			},
+		},
+		{
+			name:  "with format",
+			input: "-format atom -body text meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:       "meain/dotfiles",
+					Modes:      Modes{true, true, true, true, true, true, true},
+					Format:     "atom",
+					BodyFormat: "text",
+				},
+			},
+		},
+		{
+			name:  "with title template",
+			input: "-title-template numbered meain/dotfiles",
+			cfg: config{
+				RunConfig: &RunConfig{
+					Repo:          "meain/dotfiles",
+					Modes:         Modes{true, true, true, true, true, true, true},
+					TitleTemplate: "numbered",
+				},
+			},
+		},
 		{
 			name:  "server",
 			input: "--server",
@@ -318,6 +764,36 @@ func TestCliFlagParsing(t *testing.T) {
 				ServerConfig: &ServerConfig{
 					Port:         0,
 					CacheTimeout: 60 * 12,
+					Cache:        "file",
+					CacheSize:    1000,
+				},
+			},
+		},
+		{
+			name:  "server with cache backend",
+			input: "--server -cache sqlite -cache-path /var/lib/gh-issues-to-rss.db",
+			cfg: config{
+				ServerConfig: &ServerConfig{
+					Port:         0,
+					CacheTimeout: 60 * 12,
+					Cache:        "sqlite",
+					CachePath:    "/var/lib/gh-issues-to-rss.db",
+					CacheSize:    1000,
+				},
+			},
+		},
+		{
+			name:  "server with background refresh",
+			input: "--server -stale-while-revalidate -refresh-interval 30",
+			cfg: config{
+				ServerConfig: &ServerConfig{
+					Port:         0,
+					CacheTimeout: 60 * 12,
+					Cache:        "file",
+					CacheSize:    1000,
+
+					StaleWhileRevalidate: true,
+					RefreshInterval:      30,
 				},
 			},
 		},
@@ -328,6 +804,8 @@ func TestCliFlagParsing(t *testing.T) {
 				ServerConfig: &ServerConfig{
 					Port:         8081,
 					CacheTimeout: 120,
+					Cache:        "file",
+					CacheSize:    1000,
 				},
 			},
 		},
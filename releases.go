@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// getReleases returns the releases of a repo, newest first
+func getReleases(repo string, cacheTimeout time.Duration) ([]GithubRelease, error) {
+	content, err := getCached(repo+"/releases.json", cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		fmt.Println("Fetching releases for " + repo + " from Github")
+		return fetchAll(baseUrl+repo+"/releases?per_page=100", meta, maxPages)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []GithubRelease
+	if err := json.Unmarshal(content, &releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+func getReleaseFeed(rc RunConfig, cacheTimeout time.Duration) (string, error) {
+	if rc.Repo == "" {
+		return "", errors.New("release feeds are only supported for single repos")
+	}
+
+	releases, err := getReleases(rc.Repo, cacheTimeout)
+	if err != nil {
+		return "", err
+	}
+	return generateReleaseRss(releases, rc)
+}
+
+// releaseEvent is what happened to the release as used in titles,
+// empty if the release should not be in the feed
+func releaseEvent(release GithubRelease, rc RunConfig) string {
+	switch {
+	case release.Draft:
+		if rc.Drafts {
+			return "draft"
+		}
+	case release.Prerelease:
+		if rc.PreReleases {
+			return "prerelease"
+		}
+	default:
+		return "published"
+	}
+	return ""
+}
+
+// generateReleaseRss creates a feed with an item per release. Only
+// published releases are included unless pre-releases or drafts are
+// asked for.
+func generateReleaseRss(releases []GithubRelease, rc RunConfig) (string, error) {
+	now := time.Now()
+	feed := &feeds.Feed{
+		Title:   rc.Repo + " releases",
+		Link:    &feeds.Link{Href: "https://github.com/" + rc.Repo + "/releases"},
+		Created: now,
+	}
+
+	titleTmpl := rc.TitleTemplate
+	if titleTmpl == "" {
+		titleTmpl = titleTemplate
+	}
+	tmpl, err := parseTitleTemplate(titleTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var items []*feeds.Item
+	for _, release := range releases {
+		event := releaseEvent(release, rc)
+		if event == "" {
+			continue
+		}
+
+		name := release.Name
+		if name == "" {
+			name = release.TagName
+		}
+		title, err := renderTitle(tmpl, titleData{rc.Repo, 0, "release", event, name, nil, release.Author.Login})
+		if err != nil {
+			return "", err
+		}
+
+		// drafts are not published yet
+		published := release.PublishedAt
+		if published == "" {
+			published = release.CreatedAt
+		}
+		createTime, _ := time.Parse("2006-01-02T15:04:05Z07:00", published)
+
+		body := renderBody(release.Body, release.HTMLURL, rc.BodyFormat)
+		items = append(items, &feeds.Item{
+			Title:       title,
+			Link:        &feeds.Link{Href: release.HTMLURL},
+			Description: body,
+			Content:     body,
+			Author:      &feeds.Author{Name: release.Author.Login},
+			Id:          release.HTMLURL + "#" + event, // pre-releases can later be published
+			Created:     createTime,
+		})
+	}
+
+	sortItems(items)
+	feed.Items, err = limitItems(items, rc, now)
+	if err != nil {
+		return "", err
+	}
+
+	return renderFeed(feed, nil, rc.Format)
+}
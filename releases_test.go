@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestReleaseRssGeneration(t *testing.T) {
+	releases := []GithubRelease{
+		GithubRelease{
+			Name:        "v1.1.0-rc1",
+			TagName:     "v1.1.0-rc1",
+			Body:        "Try **this** out",
+			HTMLURL:     "https://github.com/meain/dotfiles/releases/tag/v1.1.0-rc1",
+			Prerelease:  true,
+			CreatedAt:   "2021-09-09T12:44:47Z",
+			PublishedAt: "2021-09-09T12:44:47Z",
+			Author:      GithubUser{Login: "meain"},
+		},
+		GithubRelease{
+			TagName:   "untagged-1234",
+			HTMLURL:   "https://github.com/meain/dotfiles/releases/tag/untagged-1234",
+			Draft:     true,
+			CreatedAt: "2021-09-10T12:44:47Z",
+			Author:    GithubUser{Login: "meain"},
+		},
+		GithubRelease{
+			Name:        "First release",
+			TagName:     "v1.0.0",
+			Body:        "Initial release",
+			HTMLURL:     "https://github.com/meain/dotfiles/releases/tag/v1.0.0",
+			CreatedAt:   "2021-09-08T12:44:47Z",
+			PublishedAt: "2021-09-08T12:44:47Z",
+			Author:      GithubUser{Login: "meain"},
+		},
+	}
+
+	table := []struct {
+		name     string
+		rc       RunConfig
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "published",
+			rc:   RunConfig{Repo: "meain/dotfiles"},
+			contains: []string{
+				"<title>meain/dotfiles releases</title>",
+				"<title>[release-published]: First release</title>",
+				"<guid>https://github.com/meain/dotfiles/releases/tag/v1.0.0#published</guid>",
+				"<link>https://github.com/meain/dotfiles/releases/tag/v1.0.0</link>",
+			},
+			excludes: []string{"v1.1.0-rc1", "untagged-1234"},
+		},
+		{
+			name: "with prereleases and drafts",
+			rc:   RunConfig{Repo: "meain/dotfiles", PreReleases: true, Drafts: true},
+			contains: []string{
+				"<title>[release-prerelease]: v1.1.0-rc1</title>",
+				"<title>[release-draft]: untagged-1234</title>",
+				"&lt;strong&gt;this&lt;/strong&gt;",
+			},
+		},
+		{
+			name:     "raw body",
+			rc:       RunConfig{Repo: "meain/dotfiles", PreReleases: true, BodyFormat: "raw"},
+			contains: []string{"Try **this** out"},
+		},
+		{
+			name:     "limit",
+			rc:       RunConfig{Repo: "meain/dotfiles", PreReleases: true, Limit: 1},
+			contains: []string{"v1.1.0-rc1"},
+			excludes: []string{"First release"},
+		},
+		{
+			name:     "numbered",
+			rc:       RunConfig{Repo: "meain/dotfiles", TitleTemplate: "numbered"},
+			contains: []string{"<title>First release (meain/dotfiles)</title>"},
+		},
+		{
+			name:     "title template",
+			rc:       RunConfig{Repo: "meain/dotfiles", TitleTemplate: "{{.Repo}} {{.Title}} by {{.Author}}"},
+			contains: []string{"<title>meain/dotfiles First release by meain</title>"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := generateReleaseRss(releases, tc.rc)
+			if err != nil {
+				t.Fatalf("Unable to generate feed: %s", err)
+			}
+			for _, c := range tc.contains {
+				if !strings.Contains(content, c) {
+					t.Fatalf("Expected %v in feed: %s", c, content)
+				}
+			}
+			for _, c := range tc.excludes {
+				if strings.Contains(content, c) {
+					t.Fatalf("Did not expect %v in feed: %s", c, content)
+				}
+			}
+		})
+	}
+}
+
+func TestGetReleaseFeed(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/releases$").
+		Reply(200).
+		BodyString(`[{"name":"v1.0.0","tag_name":"v1.0.0","html_url":"https://github.com/meain/dotfiles/releases/tag/v1.0.0","published_at":"2021-09-08T12:44:47Z"}]`)
+
+	rc := RunConfig{Repo: "meain/dotfiles", Releases: true, Format: "atom"}
+	content, err := getIssueFeed(rc, time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "<title>[release-published]: v1.0.0</title>") {
+		t.Fatalf("Release missing from feed: %s", content)
+	}
+
+	// served from cache and so no more requests
+	if _, err := getIssueFeed(rc, time.Hour); err != nil {
+		t.Fatalf("Unable to generate feed from cache: %s", err)
+	}
+
+	if _, err := getIssueFeed(RunConfig{Repos: []string{"meain/dotfiles"}, Releases: true}, time.Hour); err == nil {
+		t.Fatalf("Expected error for release feed without a single repo")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Supported ways of rendering issue bodies
+var bodyFormats = []string{"html", "text", "raw"}
+
+// Context key holding the url relative links are resolved against
+var baseUrlKey = parser.NewContextKey()
+
+// absoluteLinks rewrites relative link and image destinations into
+// absolute ones so that they work from within feed readers
+type absoluteLinks struct{}
+
+func (absoluteLinks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	base, ok := pc.Get(baseUrlKey).(*url.URL)
+	if !ok || base == nil {
+		return
+	}
+
+	resolve := func(dest []byte) []byte {
+		u, err := url.Parse(string(dest))
+		if err != nil || u.IsAbs() || strings.HasPrefix(string(dest), "#") {
+			return dest
+		}
+		return []byte(base.ResolveReference(u).String())
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			node.Destination = resolve(node.Destination)
+		case *ast.Image:
+			node.Destination = resolve(node.Destination)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(absoluteLinks{}, 100)),
+	),
+	goldmark.WithRendererOptions(
+		// Github treats newlines in issues and comments as line breaks
+		goldmarkhtml.WithHardWraps(),
+		// raw html is passed through here, but gets sanitized later
+		goldmarkhtml.WithUnsafe(),
+	),
+)
+
+var htmlPolicy = newHtmlPolicy()
+var textPolicy = bluemonday.StrictPolicy()
+
+func newHtmlPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// keep language hints on code blocks for readers which highlight them
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	return p
+}
+
+// renderBody converts the Github flavored markdown body of an issue
+// into what we put in the feed. `format` is one of bodyFormats (html
+// if empty) and `base` is the url relative links are resolved against.
+func renderBody(body string, base string, format string) string {
+	if format == "raw" {
+		return body
+	}
+
+	ctx := parser.NewContext()
+	if u, err := url.Parse(base); err == nil && u.IsAbs() {
+		ctx.Set(baseUrlKey, u)
+	}
+
+	var buf bytes.Buffer
+	err := markdown.Convert([]byte(body), &buf, parser.WithContext(ctx))
+	if err != nil {
+		return html.EscapeString(body)
+	}
+
+	// The text is left escaped as readers treat it as html too
+	if format == "text" {
+		return strings.TrimSpace(textPolicy.Sanitize(buf.String()))
+	}
+	return strings.TrimSpace(htmlPolicy.Sanitize(buf.String()))
+}
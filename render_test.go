@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderBody(t *testing.T) {
+	base := "https://github.com/meain/dotfiles/issues/12"
+
+	table := []struct {
+		name     string
+		body     string
+		format   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "markdown",
+			body:     "Some **bold** text\nnext line\n\n```go\nfmt.Println()\n```",
+			contains: []string{"<strong>bold</strong>", "text<br>", "<pre><code class=\"language-go\">fmt.Println()"},
+		},
+		{
+			name:     "gfm",
+			body:     "| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] done\n\nhttps://example.com",
+			contains: []string{"<table>", "<td>1</td>", "<li> done</li>", `<a href="https://example.com"`},
+		},
+		{
+			name: "relative links",
+			body: "[other](/meain/dotfiles/pull/1) ![img](screenshot.png) [top](#readme)",
+			contains: []string{
+				`href="https://github.com/meain/dotfiles/pull/1"`,
+				`src="https://github.com/meain/dotfiles/issues/screenshot.png"`,
+				`href="#readme"`,
+			},
+		},
+		{
+			name:     "sanitized",
+			body:     "hello <script>alert(1)</script><img src=\"https://example.com/a.png\" onerror=\"alert(1)\"> <details><summary>more</summary>stuff</details>",
+			contains: []string{`<img src="https://example.com/a.png">`, "<details><summary>more</summary>"},
+			excludes: []string{"<script>", "onerror", "alert(1)"},
+		},
+		{
+			name:     "text",
+			body:     "Some **bold** <b>text</b> & more",
+			format:   "text",
+			contains: []string{"Some bold text &amp; more"},
+			excludes: []string{"<"},
+		},
+		{
+			name:     "text stays escaped",
+			body:     "see `<script>alert(1)</script>` and &lt;img src=x onerror=alert(1)&gt;",
+			format:   "text",
+			contains: []string{"&lt;script&gt;", "&lt;img"},
+			excludes: []string{"<"},
+		},
+		{
+			name:     "raw",
+			body:     "Some **bold** <b>text</b>",
+			format:   "raw",
+			contains: []string{"Some **bold** <b>text</b>"},
+		},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderBody(tc.body, base, tc.format)
+			for _, c := range tc.contains {
+				if !strings.Contains(got, c) {
+					t.Fatalf("Expected %v in rendered body: %s", c, got)
+				}
+			}
+			for _, c := range tc.excludes {
+				if strings.Contains(got, c) {
+					t.Fatalf("Did not expect %v in rendered body: %s", c, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Max number of pages (100 repos each) to fetch when listing the repos
+// of an org or user
+var maxRepoPages = 10
+
+// Owner types which have their own feeds and the Github API path used
+// to list their repos
+var ownerTypes = map[string]string{
+	"org":  "orgs",
+	"user": "users",
+}
+
+// reposKey is where the repo list of an owner is cached. Github logins
+// can't start with an underscore and so this can't clash with a repo.
+func reposKey(ownerType string, owner string) string {
+	return "_" + ownerTypes[ownerType] + "/" + owner + "/repos.json"
+}
+
+// getRepoList returns the repos of an org or user, cached separately
+// from the issues of those repos
+func getRepoList(ownerType string, owner string, cacheTimeout time.Duration) ([]GithubRepo, error) {
+	content, err := getCached(reposKey(ownerType, owner), cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		fmt.Println("Fetching repos of " + ownerType + " " + owner + " from Github")
+		url := apiUrl + ownerTypes[ownerType] + "/" + owner + "/repos?per_page=100"
+		return fetchAll(url, meta, maxRepoPages)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []GithubRepo
+	if err := json.Unmarshal(content, &repos); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// selectRepos picks the repos to include in an owner wide feed. Forks
+// and archived repos are skipped unless asked for. Include and exclude
+// patterns are globs matched against either the name or the full name
+// of the repo.
+func selectRepos(repos []GithubRepo, rc RunConfig) []string {
+	var include, exclude []*regexp.Regexp
+	for _, glob := range rc.IncludeRepos {
+		include = append(include, globRegexp(glob))
+	}
+	for _, glob := range rc.ExcludeRepos {
+		exclude = append(exclude, globRegexp(glob))
+	}
+
+	matches := func(repo GithubRepo, patterns []*regexp.Regexp) bool {
+		for _, re := range patterns {
+			if re.MatchString(repo.Name) || re.MatchString(repo.FullName) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected []string
+	for _, repo := range repos {
+		if repo.Archived && !rc.Archived {
+			continue
+		}
+		if repo.Fork && !rc.Forks {
+			continue
+		}
+		if len(include) > 0 && !matches(repo, include) {
+			continue
+		}
+		if matches(repo, exclude) {
+			continue
+		}
+		selected = append(selected, repo.FullName)
+	}
+
+	return selected
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSelectRepos(t *testing.T) {
+	repos := []GithubRepo{
+		{Name: "dotfiles", FullName: "meain/dotfiles"},
+		{Name: "gh-issues-to-rss", FullName: "meain/gh-issues-to-rss"},
+		{Name: "evil-textobj-tree-sitter", FullName: "meain/evil-textobj-tree-sitter"},
+		{Name: "old-dotfiles", FullName: "meain/old-dotfiles", Archived: true},
+		{Name: "neovim", FullName: "meain/neovim", Fork: true},
+	}
+
+	table := []struct {
+		name     string
+		rc       RunConfig
+		selected string
+	}{
+		{"default", RunConfig{}, "meain/dotfiles,meain/gh-issues-to-rss,meain/evil-textobj-tree-sitter"},
+		{"archived", RunConfig{Archived: true}, "meain/dotfiles,meain/gh-issues-to-rss,meain/evil-textobj-tree-sitter,meain/old-dotfiles"},
+		{"forks", RunConfig{Forks: true}, "meain/dotfiles,meain/gh-issues-to-rss,meain/evil-textobj-tree-sitter,meain/neovim"},
+		{"include", RunConfig{IncludeRepos: []string{"*dotfiles", "GH-*"}, Archived: true}, "meain/dotfiles,meain/gh-issues-to-rss,meain/old-dotfiles"},
+		{"include full name", RunConfig{IncludeRepos: []string{"meain/evil-*"}}, "meain/evil-textobj-tree-sitter"},
+		{"exclude", RunConfig{ExcludeRepos: []string{"*-tree-sitter", "dotfiles"}}, "meain/gh-issues-to-rss"},
+	}
+
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			got := strings.Join(selectRepos(repos, tc.rc), ",")
+			if got != tc.selected {
+				t.Fatalf("Expected %v, got %v", tc.selected, got)
+			}
+		})
+	}
+}
+
+func TestGetRepoList(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/orgs/meain/repos").
+		Reply(200).
+		SetHeader("Link", `<https://api.github.com/orgs/meain/repos?per_page=100&page=2>; rel="next"`).
+		BodyString(`[{"name":"dotfiles","full_name":"meain/dotfiles"}]`)
+	gock.New("https://api.github.com").
+		Get("/orgs/meain/repos").
+		MatchParam("page", "2").
+		Reply(200).
+		BodyString(`[{"name":"neovim","full_name":"meain/neovim","fork":true}]`)
+
+	repos, err := getRepoList("org", "meain", time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to get repo list: %s", err)
+	}
+	if len(repos) != 2 || repos[0].FullName != "meain/dotfiles" || !repos[1].Fork {
+		t.Fatalf("Invalid repo list: %v", repos)
+	}
+	if !gock.IsDone() {
+		t.Fatalf("Not all pages were fetched")
+	}
+
+	// served from cache and so no more requests
+	repos, err = getRepoList("org", "meain", time.Hour)
+	if err != nil || len(repos) != 2 {
+		t.Fatalf("Repo list not cached: %v %v", repos, err)
+	}
+
+	if _, _, err := cache.Load("meain/repos.json"); err == nil {
+		t.Fatalf("Repo list cached under the key of a repo")
+	}
+}
+
+func TestGetIssueFeedOrg(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/users/meain/repos").
+		Reply(200).
+		BodyString(`[{"name":"dotfiles","full_name":"meain/dotfiles"},{"name":"neovim","full_name":"meain/neovim","fork":true}]`)
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues$").
+		Reply(200).
+		BodyString(`[{"number":1,"title":"Sample Entry","state":"open","created_at":"2021-09-01T12:44:47Z"}]`)
+
+	rc := RunConfig{Owner: "meain", OwnerType: "user", Modes: Modes{true, true, true, true, true, true, true}}
+	content, err := getIssueFeed(rc, 0)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+	if !strings.Contains(content, "<title>[meain/dotfiles] [issue-open]: Sample Entry</title>") {
+		t.Fatalf("Issue missing from user feed: %s", content)
+	}
+	if !strings.Contains(content, "<link>https://github.com/meain</link>") {
+		t.Fatalf("Invalid link for user feed: %s", content)
+	}
+
+	gock.New("https://api.github.com").
+		Get("/users/meain/repos").
+		Reply(200).
+		BodyString(`[{"name":"neovim","full_name":"meain/neovim","fork":true}]`)
+	if _, err := getIssueFeed(rc, 0); err == nil {
+		t.Fatalf("Expected error when there are no repos")
+	}
+}
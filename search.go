@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Response of the Github search API, results are wrapped in an object
+type githubSearchResult struct {
+	IncompleteResults bool              `json:"incomplete_results"`
+	Items             []json.RawMessage `json:"items"`
+	TotalCount        int64             `json:"total_count"`
+}
+
+// searchKey is where search results are cached. Queries can contain
+// pretty much anything and so we use a hash of it.
+func searchKey(query string) string {
+	sum := sha1.Sum([]byte(query))
+	return "_search/" + hex.EncodeToString(sum[:]) + "/issues.json"
+}
+
+// makeSearchRequest fetches the issues matching a Github search query,
+// most recently updated first
+func makeSearchRequest(query string, meta cacheMeta) ([]byte, cacheMeta, error) {
+	u := apiUrl + "search/issues?sort=updated&order=desc&per_page=100&q=" + url.QueryEscape(query)
+	pages, meta, _, err := fetchPages(u, meta, maxPages)
+	if err != nil {
+		return nil, cacheMeta{}, err
+	}
+
+	for i, page := range pages {
+		var result githubSearchResult
+		if err := json.Unmarshal(page, &result); err != nil {
+			return nil, cacheMeta{}, err
+		}
+		if pages[i], err = json.Marshal(result.Items); err != nil {
+			return nil, cacheMeta{}, err
+		}
+	}
+
+	content, err := mergePages(pages)
+	if err != nil {
+		return nil, cacheMeta{}, err
+	}
+	return content, meta, nil
+}
+
+// getSearchIssues returns the issues matching a Github search query
+func getSearchIssues(query string, cacheTimeout time.Duration) ([]GithubIssue, error) {
+	content, err := getCached(searchKey(query), cacheTimeout, func(meta cacheMeta) ([]byte, cacheMeta, error) {
+		fmt.Println("Searching Github for " + query)
+		return makeSearchRequest(query, meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := []GithubIssue{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+
+	// search results can be from any repo
+	for i := range data {
+		splits := strings.SplitN(data[i].RepositoryURL, "/repos/", 2)
+		if len(splits) == 2 {
+			data[i].Repo = splits[1]
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestMakeSearchRequest(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		MatchParam("q", "is:pr review-requested:meain").
+		MatchParam("page", "2").
+		Reply(200).
+		BodyString(`{"total_count":2,"incomplete_results":false,"items":[{"number":2}]}`)
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		MatchParam("q", "is:pr review-requested:meain").
+		Reply(200).
+		SetHeader("Link", `<https://api.github.com/search/issues?q=is%3Apr+review-requested%3Ameain&page=2>; rel="next"`).
+		BodyString(`{"total_count":2,"incomplete_results":false,"items":[{"number":1}]}`)
+
+	content, _, err := makeSearchRequest("is:pr review-requested:meain", cacheMeta{})
+	if err != nil {
+		t.Fatalf("Unable to search: %s", err)
+	}
+	if string(content) != `[{"number":1},{"number":2}]` {
+		t.Fatalf("Invalid search results: %s", content)
+	}
+}
+
+func TestGetSearchIssues(t *testing.T) {
+	cacheBackup := cache
+	defer func() { cache = cacheBackup }()
+	cache = newMemoryCache(10)
+
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		Reply(200).
+		BodyString(`{"total_count":2,"items":[` +
+			`{"number":1,"title":"Older Entry","state":"open","created_at":"2021-09-01T12:44:47Z","repository_url":"https://api.github.com/repos/meain/dotfiles"},` +
+			`{"number":7,"title":"Newer Entry","state":"open","created_at":"2021-09-05T12:44:47Z","repository_url":"https://api.github.com/repos/meain/gh-issues-to-rss","pull_request":{"url":"https://api.github.com/repos/meain/gh-issues-to-rss/pulls/7"}}]}`)
+
+	issues, err := getSearchIssues("author:meain", time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to search: %s", err)
+	}
+	if len(issues) != 2 || issues[0].Repo != "meain/dotfiles" || issues[1].Repo != "meain/gh-issues-to-rss" {
+		t.Fatalf("Invalid search results: %v", issues)
+	}
+
+	// served from cache and so no more requests
+	content, err := getIssueFeed(RunConfig{Search: "author:meain", Modes: Modes{true, true, true, true, true, true, true}}, time.Hour)
+	if err != nil {
+		t.Fatalf("Unable to generate feed: %s", err)
+	}
+
+	newer := strings.Index(content, "<title>[meain/gh-issues-to-rss] [pr-open]: Newer Entry</title>")
+	older := strings.Index(content, "<title>[meain/dotfiles] [issue-open]: Older Entry</title>")
+	if newer == -1 || older == -1 || newer > older {
+		t.Fatalf("Invalid search feed: %s", content)
+	}
+	if !strings.Contains(content, "<guid>https://github.com/meain/gh-issues-to-rss/issues/7#open</guid>") {
+		t.Fatalf("Items in search feed do not use their own repo for guid: %s", content)
+	}
+	if !strings.Contains(content, "<title>Search: author:meain</title>") {
+		t.Fatalf("Invalid title for search feed: %s", content)
+	}
+}
+
+func TestSearchKey(t *testing.T) {
+	if searchKey("is:pr") == searchKey("is:issue") {
+		t.Fatalf("Different queries using the same cache key")
+	}
+	if !strings.HasPrefix(searchKey("is:pr ../../etc"), "_search/") || strings.Contains(searchKey("is:pr ../../etc"), "..") {
+		t.Fatalf("Query not hashed in cache key: %s", searchKey("is:pr ../../etc"))
+	}
+}
+
+func TestSearchRateLimitSeparate(t *testing.T) {
+	defer func() { rateLimitedUntil = map[string]time.Time{} }()
+
+	reset := time.Now().Add(time.Minute).Unix()
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/search/issues").
+		Reply(403).
+		SetHeader("X-RateLimit-Remaining", "0").
+		SetHeader("X-RateLimit-Resource", "search").
+		SetHeader("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+	gock.New("https://api.github.com").
+		Get("/repos/meain/dotfiles/issues$").
+		Reply(200).
+		BodyString(`[]`)
+
+	var rle *RateLimitError
+	if _, _, err := makeSearchRequest("is:pr", cacheMeta{}); !errors.As(err, &rle) {
+		t.Fatalf("Expected rate limit error, got %v", err)
+	}
+	if _, _, err := makeSearchRequest("is:issue", cacheMeta{}); !errors.As(err, &rle) {
+		t.Fatalf("Expected rate limit error without request, got %v", err)
+	}
+
+	// repo requests use a different limit
+	if _, _, err := makeRequest("meain/dotfiles", "", cacheMeta{}); err != nil {
+		t.Fatalf("Repo request blocked by search rate limit: %s", err)
+	}
+}
@@ -1,26 +1,73 @@
 package main
 
 type Modes struct {
-	IssueOpen    bool
-	IssuesClosed bool
-	PROpen       bool
-	PRClosed     bool
+	IssueOpen     bool
+	IssuesClosed  bool
+	PROpen        bool
+	PRClosed      bool // closed without being merged
+	PRMerged      bool
+	IssueReopened bool
+	PRReopened    bool
 }
 
 // If running as a server
 type ServerConfig struct {
 	Port         int
 	CacheTimeout int64
+	Cache        string // one of file, memory or sqlite
+	CachePath    string
+	CacheSize    int
+
+	TitleTemplate string // default title template for feeds
+
+	StaleWhileRevalidate bool
+	RefreshInterval      int64 // in minutes
 }
 
 // If running on individual repo
 type RunConfig struct {
 	Modes     Modes
 	Repo      string
+	Repos     []string // for combined feeds, Repo is empty if set
+	Owner     string   // for owner wide feeds, Repo is empty if set
+	OwnerType string   // one of org or user
+	Search    string   // Github search query for search feeds
+	Issue     int64    // single issue to create comment feed for
+	Comments  bool     // items for comments instead of opens and closes
 	Labels    []string
 	NotLabels []string
 	Users     []string
 	NotUsers  []string
+	Text      []string // matched against title and body
+	NotText   []string
+
+	Assignees       []string // "unassigned" for issues without assignees
+	NotAssignees    []string
+	Milestones      []string // "none" for issues without a milestone
+	NotMilestones   []string
+	Associations    []string // author association like MEMBER or FIRST_TIME_CONTRIBUTOR
+	NotAssociations []string
+	NoBots          bool
+
+	// Release feeds, items for releases instead of issues
+	Releases    bool
+	PreReleases bool
+	Drafts      bool
+
+	// Picking repos for owner wide feeds
+	IncludeRepos []string
+	ExcludeRepos []string
+	Archived     bool
+	Forks        bool
+
+	Query      string // filter expression, see filterNode
+	Format     string // one of rss, atom or json; rss if empty
+	BodyFormat string // one of html, text or raw; html if empty
+	Since      string // absolute or relative time, see parseTimeBound
+	Until      string
+	Limit      int // max number of items, 0 for no limit
+
+	TitleTemplate string // preset name or template; titleTemplate if empty
 }
 
 type config struct {
@@ -28,14 +75,106 @@ type config struct {
 	ServerConfig *ServerConfig
 }
 
+// Validators returned by Github which we use for conditional requests
+type cacheMeta struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
+
+type GithubUser struct {
+	AvatarURL         string `json:"avatar_url"`
+	EventsURL         string `json:"events_url"`
+	FollowersURL      string `json:"followers_url"`
+	FollowingURL      string `json:"following_url"`
+	GistsURL          string `json:"gists_url"`
+	GravatarID        string `json:"gravatar_id"`
+	HTMLURL           string `json:"html_url"`
+	ID                int64  `json:"id"`
+	Login             string `json:"login"`
+	NodeID            string `json:"node_id"`
+	OrganizationsURL  string `json:"organizations_url"`
+	ReceivedEventsURL string `json:"received_events_url"`
+	ReposURL          string `json:"repos_url"`
+	SiteAdmin         bool   `json:"site_admin"`
+	StarredURL        string `json:"starred_url"`
+	SubscriptionsURL  string `json:"subscriptions_url"`
+	Type              string `json:"type"`
+	URL               string `json:"url"`
+}
+
+type GithubRepo struct {
+	Archived bool   `json:"archived"`
+	Fork     bool   `json:"fork"`
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	Name     string `json:"name"`
+	Private  bool   `json:"private"`
+}
+
+// Github app through which an issue was created
+type GithubApp struct {
+	HTMLURL string     `json:"html_url"`
+	ID      int64      `json:"id"`
+	Name    string     `json:"name"`
+	Owner   GithubUser `json:"owner"`
+	Slug    string     `json:"slug"`
+}
+
+type GithubMilestone struct {
+	DueOn   string `json:"due_on"`
+	HTMLURL string `json:"html_url"`
+	Number  int64  `json:"number"`
+	State   string `json:"state"`
+	Title   string `json:"title"`
+}
+
 type GithubIssueLabel struct {
 	Name string `json:"name"`
 }
 
+// Events like closed or reopened on an issue
+type GithubIssueEvent struct {
+	ID        int64  `json:"id"`
+	Event     string `json:"event"`
+	CreatedAt string `json:"created_at"`
+	Issue     struct {
+		Number int64 `json:"number"`
+	} `json:"issue"`
+}
+
+type GithubComment struct {
+	AuthorAssociation     string     `json:"author_association"`
+	Body                  string     `json:"body"`
+	CreatedAt             string     `json:"created_at"`
+	HTMLURL               string     `json:"html_url"`
+	ID                    int64      `json:"id"`
+	IssueURL              string     `json:"issue_url"`
+	PerformedViaGithubApp *GithubApp `json:"performed_via_github_app"`
+	UpdatedAt             string     `json:"updated_at"`
+	URL                   string     `json:"url"`
+	User                  GithubUser `json:"user"`
+}
+
+// Drafts are only listed when the token has push access to the repo
+type GithubRelease struct {
+	Author          GithubUser `json:"author"`
+	Body            string     `json:"body"`
+	CreatedAt       string     `json:"created_at"`
+	Draft           bool       `json:"draft"`
+	HTMLURL         string     `json:"html_url"`
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Prerelease      bool       `json:"prerelease"`
+	PublishedAt     string     `json:"published_at"`
+	TagName         string     `json:"tag_name"`
+	TargetCommitish string     `json:"target_commitish"`
+	URL             string     `json:"url"`
+}
+
 type GithubIssue struct {
 	ActiveLockReason      interface{}        `json:"active_lock_reason"`
-	Assignee              interface{}        `json:"assignee"`
-	Assignees             []interface{}      `json:"assignees"`
+	Assignee              *GithubUser        `json:"assignee"`
+	Assignees             []GithubUser       `json:"assignees"`
 	AuthorAssociation     string             `json:"author_association"`
 	Body                  string             `json:"body"`
 	ClosedAt              string             `json:"closed_at"`
@@ -48,39 +187,26 @@ type GithubIssue struct {
 	Labels                []GithubIssueLabel `json:"labels"`
 	LabelsURL             string             `json:"labels_url"`
 	Locked                bool               `json:"locked"`
-	Milestone             interface{}        `json:"milestone"`
+	Milestone             *GithubMilestone   `json:"milestone"`
 	NodeID                string             `json:"node_id"`
 	Number                int64              `json:"number"`
-	PerformedViaGithubApp interface{}        `json:"performed_via_github_app"`
+	PerformedViaGithubApp *GithubApp         `json:"performed_via_github_app"`
 	PullRequest           struct {
 		DiffURL  string `json:"diff_url"`
 		HTMLURL  string `json:"html_url"`
+		MergedAt string `json:"merged_at"`
 		PatchURL string `json:"patch_url"`
 		URL      string `json:"url"`
 	} `json:"pull_request"`
-	RepositoryURL string `json:"repository_url"`
-	State         string `json:"state"`
-	Title         string `json:"title"`
-	UpdatedAt     string `json:"updated_at"`
-	URL           string `json:"url"`
-	User          struct {
-		AvatarURL         string `json:"avatar_url"`
-		EventsURL         string `json:"events_url"`
-		FollowersURL      string `json:"followers_url"`
-		FollowingURL      string `json:"following_url"`
-		GistsURL          string `json:"gists_url"`
-		GravatarID        string `json:"gravatar_id"`
-		HTMLURL           string `json:"html_url"`
-		ID                int64  `json:"id"`
-		Login             string `json:"login"`
-		NodeID            string `json:"node_id"`
-		OrganizationsURL  string `json:"organizations_url"`
-		ReceivedEventsURL string `json:"received_events_url"`
-		ReposURL          string `json:"repos_url"`
-		SiteAdmin         bool   `json:"site_admin"`
-		StarredURL        string `json:"starred_url"`
-		SubscriptionsURL  string `json:"subscriptions_url"`
-		Type              string `json:"type"`
-		URL               string `json:"url"`
-	} `json:"user"`
+	RepositoryURL string     `json:"repository_url"`
+	State         string     `json:"state"`
+	Title         string     `json:"title"`
+	UpdatedAt     string     `json:"updated_at"`
+	URL           string     `json:"url"`
+	User          GithubUser `json:"user"`
+
+	// Not part of the Github response, attached from issue events
+	Events []GithubIssueEvent `json:"-"`
+	// Not part of the Github response, repo the issue was fetched from
+	Repo string `json:"-"`
 }